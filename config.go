@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultConfigFile = ".gmail-label-fixer.yaml" // Looked up in the home directory
+	envPrefix         = "GMAIL_FIXER_"            // Prefix of environment variables that default flags
+)
+
+var configPath string
+
+// configuredFlags records flags defaulted from the environment or config file
+var configuredFlags = make(map[string]bool)
+
+// applyConfigDefaults fills in flags the user did not pass on the command line, first from
+// GMAIL_FIXER_<FLAG> environment variables and then from the config file. Precedence is
+// flag > env > file > built-in default.
+func applyConfigDefaults(cmd *cobra.Command) error {
+	settings, path, err := loadConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Settings may target flags of other commands, so only reject names no command knows
+	known := knownFlagNames(cmd.Root())
+	for name := range settings {
+		if !known[name] {
+			return fmt.Errorf("unknown setting %q in %s", name, path)
+		}
+	}
+
+	var applyErr error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed || flag.Name == "config" {
+			return
+		}
+
+		if envValue, ok := os.LookupEnv(envVarName(flag.Name)); ok {
+			values := []string{envValue}
+			if isListFlag(flag) {
+				values = strings.Split(envValue, ",")
+			}
+			applyErr = setFlag(flag, values, envVarName(flag.Name))
+			return
+		}
+
+		if value, ok := settings[flag.Name]; ok {
+			values, err := settingValues(flag, value)
+			if err != nil {
+				applyErr = fmt.Errorf("invalid setting %q in %s: %v", flag.Name, path, err)
+				return
+			}
+			applyErr = setFlag(flag, values, path)
+		}
+	})
+	return applyErr
+}
+
+// loadConfigFile reads the file named by --config, or ~/.gmail-label-fixer.yaml if it exists
+func loadConfigFile(cmd *cobra.Command) (map[string]interface{}, string, error) {
+	path := configPath
+	explicit := cmd.Flags().Changed("config")
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, "", nil // No home directory, so no default config file
+		}
+		path = filepath.Join(home, defaultConfigFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil, path, nil
+		}
+		return nil, path, fmt.Errorf("unable to read config file %s: %v", path, err)
+	}
+
+	settings := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, path, fmt.Errorf("unable to parse config file %s: %v", path, err)
+	}
+	return settings, path, nil
+}
+
+// knownFlagNames collects the flag names of every command in the tree
+func knownFlagNames(root *cobra.Command) map[string]bool {
+	known := make(map[string]bool)
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			known[flag.Name] = true
+		})
+		cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			known[flag.Name] = true
+		})
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+	return known
+}
+
+// envVarName maps a flag name such as rate-limit-delay to GMAIL_FIXER_RATE_LIMIT_DELAY
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// isListFlag reports whether the flag accepts repeated values, like --skip
+func isListFlag(flag *pflag.Flag) bool {
+	return strings.HasSuffix(flag.Value.Type(), "Slice") || strings.HasSuffix(flag.Value.Type(), "Array")
+}
+
+// settingValues converts a YAML value into flag values; lists are only allowed for repeatable flags
+func settingValues(flag *pflag.Flag, value interface{}) ([]string, error) {
+	list, isList := value.([]interface{})
+	if !isList {
+		return []string{fmt.Sprint(value)}, nil
+	}
+	if !isListFlag(flag) {
+		return nil, fmt.Errorf("expected a single value, got a list")
+	}
+
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprint(item))
+	}
+	return values, nil
+}
+
+// setFlag assigns values without marking the flag as changed on the command line
+func setFlag(flag *pflag.Flag, values []string, source string) error {
+	for _, value := range values {
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid --%s value %q from %s: %v", flag.Name, value, source, err)
+		}
+	}
+	configuredFlags[flag.Name] = true
+	return nil
+}
+
+// flagProvided reports whether the user set a flag on the command line, in the environment or in the config file
+func flagProvided(cmd *cobra.Command, name string) bool {
+	return cmd.Flags().Changed(name) || configuredFlags[name]
+}
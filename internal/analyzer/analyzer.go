@@ -1,9 +1,13 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"gmail-label-fixer/internal/gmail"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	gmailAPI "google.golang.org/api/gmail/v1"
 )
@@ -13,75 +17,495 @@ type AnalysisResult struct {
 	Transformations map[string]*LabelTransformation
 	RequiredParents []string
 	TotalMessages   int
-	SkippedLabels   []*gmailAPI.Label
+	SkippedLabels   []gmail.SkippedLabel
+	ExistingLabels  map[string]*gmailAPI.Label // All labels in the mailbox keyed by name
+	SampledFrom     int                        // Processable labels before sampling, 0 when not sampled
+	NewParents      []string                   // Parent labels the fix adds to the mailbox, sorted
+	CountErrors     map[string]error           // Labels whose message count could not be fetched, keyed by name
 }
 
 type Analyzer struct {
-	client *gmail.Client
+	client  gmail.LabelService
+	options ParseOptions
+	counts  *CountCache // nil disables count caching
+
+	// countMode is CountModeMessages or CountModeThreads
+	countMode string
+
+	// activeSince drops labels whose newest message is older than it (zero keeps every label)
+	activeSince time.Time
+
+	// concurrency is how many message counts are fetched in parallel during analysis
+	concurrency int
 }
 
-func NewAnalyzer(client *gmail.Client) *Analyzer {
+func NewAnalyzer(client gmail.LabelService) *Analyzer {
 	return &Analyzer{client: client}
 }
 
-func (a *Analyzer) AnalyzeLabels() (*AnalysisResult, error) {
-	analysis, err := a.client.FindPeriodSeparatedLabelsWithAnalysis()
+func NewAnalyzerWithOptions(client gmail.LabelService, options ParseOptions) *Analyzer {
+	return &Analyzer{client: client, options: options}
+}
+
+// SetCountCache makes message counts come from cache when fresh, and be stored in it otherwise
+func (a *Analyzer) SetCountCache(cache *CountCache) {
+	a.counts = cache
+}
+
+// SetConcurrency makes analysis fetch up to n message counts in parallel
+func (a *Analyzer) SetConcurrency(n int) {
+	a.concurrency = n
+}
+
+// SetActiveSince limits analysis to labels with a message received after cutoff
+func (a *Analyzer) SetActiveSince(cutoff time.Time) {
+	a.activeSince = cutoff
+}
+
+// FilterActive splits labels into those with a message since the SetActiveSince cutoff and those
+// without. Each label costs a GetNewestMessageDate call; without a cutoff every label is active.
+func (a *Analyzer) FilterActive(ctx context.Context, labels []*gmailAPI.Label) ([]*gmailAPI.Label, []gmail.SkippedLabel, error) {
+	if a.activeSince.IsZero() {
+		return labels, nil, nil
+	}
+
+	var active []*gmailAPI.Label
+	var inactive []gmail.SkippedLabel
+	for _, label := range labels {
+		newest, err := a.client.GetNewestMessageDate(ctx, label.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if newest.After(a.activeSince) {
+			active = append(active, label)
+		} else {
+			inactive = append(inactive, gmail.SkippedLabel{Label: label, Reason: gmail.SkipReasonInactive})
+		}
+	}
+	return active, inactive, nil
+}
+
+// Ways of counting a label's messages, for the --count-mode flag
+const (
+	CountModeMessages = "messages" // Individual messages, from the label's own total (default)
+	CountModeThreads  = "threads"  // Conversations, as Gmail's conversation view counts them
+)
+
+// CountModes lists the supported values for the --count-mode flag
+var CountModes = []string{CountModeMessages, CountModeThreads}
+
+// SetCountMode makes MessageCount count messages or threads
+func (a *Analyzer) SetCountMode(mode string) {
+	a.countMode = mode
+}
+
+// countKey is the count cache key of a label; thread counts are cached apart from message counts
+func (a *Analyzer) countKey(labelID string) string {
+	if a.countMode == CountModeThreads {
+		return threadCountKey(labelID)
+	}
+	return labelID
+}
+
+func threadCountKey(labelID string) string {
+	return "threads:" + labelID
+}
+
+// MessageCount returns a label's message count, or thread count under CountModeThreads, from the
+// count cache when it is fresh
+func (a *Analyzer) MessageCount(ctx context.Context, labelID string) (int, error) {
+	key := a.countKey(labelID)
+	if a.counts != nil {
+		if count, cached := a.counts.Get(key); cached {
+			return count, nil
+		}
+	}
+
+	var count int
+	var err error
+	if a.countMode == CountModeThreads {
+		count, err = a.client.GetThreadCountWithLabel(ctx, labelID)
+	} else {
+		count, err = a.client.GetLabelMessageCount(ctx, labelID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if a.counts != nil {
+		a.counts.Put(key, count)
+	}
+	return count, nil
+}
+
+// ForgetCount drops a label's cached message count after a change that may affect it
+func (a *Analyzer) ForgetCount(labelID string) {
+	if a.counts != nil {
+		a.counts.Invalidate(labelID)
+		a.counts.Invalidate(threadCountKey(labelID))
+	}
+}
+
+// Parse converts a label name using the analyzer's parse options
+func (a *Analyzer) Parse(labelName string) *LabelTransformation {
+	return ParseLabelHierarchyWithOptions(labelName, a.options)
+}
+
+func (a *Analyzer) AnalyzeLabels(ctx context.Context) (*AnalysisResult, error) {
+	return a.AnalyzeLabelsSample(ctx, 0)
+}
+
+// AnalyzeLabelsSample analyzes only the first sample processable labels by name, skipping the
+// message counts of the rest. A sample of 0 analyzes every label.
+func (a *Analyzer) AnalyzeLabelsSample(ctx context.Context, sample int) (*AnalysisResult, error) {
+	analysis, err := a.client.FindPeriodSeparatedLabelsWithAnalysis(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find period-separated labels: %v", err)
 	}
 
-	periodLabels := analysis.ProcessableLabels
+	periodLabels, inactive, err := a.FilterActive(ctx, analysis.ProcessableLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check label activity: %v", err)
+	}
+	sampledFrom := 0
+	if sample > 0 && sample < len(periodLabels) {
+		sorted := make([]*gmailAPI.Label, len(periodLabels))
+		copy(sorted, periodLabels)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+		sampledFrom = len(periodLabels)
+		periodLabels = sorted[:sample]
+	}
 
 	transformations := make(map[string]*LabelTransformation)
-	totalMessages := 0
-
 	for _, label := range periodLabels {
-		transformation := ParseLabelHierarchy(label.Name)
+		transformation := a.Parse(label.Name)
 		if transformation != nil {
 			transformation.OriginalID = label.Id
-
-			// Get message count for this label
-			messageIDs, err := a.client.GetMessagesWithLabel(label.Id)
-			if err != nil {
-				// Only log warnings for labels that might have significant message counts
-				transformation.MessageCount = 0
-			} else {
-				transformation.MessageCount = len(messageIDs)
-				totalMessages += len(messageIDs)
-			}
-
 			transformations[label.Name] = transformation
 		}
 	}
+	totalMessages, countErrors := a.countMessages(ctx, transformations)
 
 	requiredParents := GetAllRequiredParents(transformations)
 	sort.Strings(requiredParents)
+	existingLabels := IndexLabelsByName(analysis.AllLabels)
 
 	return &AnalysisResult{
 		PeriodLabels:    periodLabels,
 		Transformations: transformations,
 		RequiredParents: requiredParents,
 		TotalMessages:   totalMessages,
-		SkippedLabels:   analysis.SkippedLabels,
+		SkippedLabels:   append(analysis.SkippedLabels, inactive...),
+		ExistingLabels:  existingLabels,
+		SampledFrom:     sampledFrom,
+		NewParents:      NewParentLabels(transformations, existingLabels),
+		CountErrors:     countErrors,
 	}, nil
 }
 
-func (a *Analyzer) CheckConflicts(transformations map[string]*LabelTransformation) []string {
+// countMessages fills in the message count of each transformation, fetching up to the
+// SetConcurrency number of counts in parallel. A label whose count fails keeps a count of 0
+// and is reported in the returned errors instead of aborting the analysis.
+func (a *Analyzer) countMessages(ctx context.Context, transformations map[string]*LabelTransformation) (int, map[string]error) {
+	workers := a.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	total := 0
+	var countErrors map[string]error
+
+	queue := make(chan *LabelTransformation)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for transformation := range queue {
+				count, err := a.MessageCount(ctx, transformation.OriginalID)
+
+				mu.Lock()
+				if err != nil {
+					if countErrors == nil {
+						countErrors = make(map[string]error)
+					}
+					countErrors[transformation.OriginalLabel] = err
+				} else {
+					transformation.MessageCount = count
+					total += count
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, transformation := range transformations {
+		queue <- transformation
+	}
+	close(queue)
+	wg.Wait()
+
+	return total, countErrors
+}
+
+// IndexLabelsByName builds a lookup of labels keyed by their full name
+func IndexLabelsByName(labels []*gmailAPI.Label) map[string]*gmailAPI.Label {
+	index := make(map[string]*gmailAPI.Label, len(labels))
+	for _, label := range labels {
+		index[label.Name] = label
+	}
+	return index
+}
+
+// NewParentLabels returns the required parents that neither exist already nor are the target of
+// a transformation, i.e. the labels a fix adds to the mailbox. Renames keep the label count.
+func NewParentLabels(transformations map[string]*LabelTransformation, existingLabels map[string]*gmailAPI.Label) []string {
+	lookup := normalizedLookup(existingLabels)
+	targets := make(map[string]bool, len(transformations))
+	for _, transformation := range transformations {
+		targets[gmail.NormalizeLabelName(transformation.NestedStructure)] = true
+	}
+
+	newParents := []string{}
+	for _, parent := range GetAllRequiredParents(transformations) {
+		if _, exists := lookup(parent); exists || targets[gmail.NormalizeLabelName(parent)] {
+			continue
+		}
+		newParents = append(newParents, parent)
+	}
+	sort.Strings(newParents)
+	return newParents
+}
+
+// CheckConflicts compares transformations against the existing labels, keyed by name
+func (a *Analyzer) CheckConflicts(transformations map[string]*LabelTransformation, existingLabels map[string]*gmailAPI.Label) []string {
 	var conflicts []string
 
-	// Check if any required parent names conflict with existing labels
+	lookup := normalizedLookup(existingLabels)
 	for _, transformation := range transformations {
-		for _, parentName := range transformation.RequiredParents {
-			if existingLabel, exists := a.client.LabelExists(parentName); exists {
-				conflicts = append(conflicts, fmt.Sprintf("Parent label '%s' already exists (ID: %s)", parentName, existingLabel.Id))
+		conflicts = append(conflicts, transformationConflicts(transformation, lookup)...)
+	}
+
+	// Check for transformations in this batch that claim the same target
+	conflicts = append(conflicts, FindCollisions(transformations)...)
+
+	return conflicts
+}
+
+// FindConflictingLabels returns the original names of the transformations CheckConflicts reports a
+// problem for, including those colliding with another transformation
+func (a *Analyzer) FindConflictingLabels(transformations map[string]*LabelTransformation, existingLabels map[string]*gmailAPI.Label) map[string]bool {
+	conflicting := make(map[string]bool)
+	for label := range ConflictReasons(transformations, existingLabels) {
+		conflicting[label] = true
+	}
+	return conflicting
+}
+
+// ConflictReasons lists the conflicts of each transformation that has any, keyed by original
+// name, including collisions with other transformations over the same target
+func ConflictReasons(transformations map[string]*LabelTransformation, existingLabels map[string]*gmailAPI.Label) map[string][]string {
+	reasons := make(map[string][]string)
+
+	lookup := normalizedLookup(existingLabels)
+	sourcesByTarget := make(map[string][]string)
+	for label, transformation := range transformations {
+		if conflicts := transformationConflicts(transformation, lookup); len(conflicts) > 0 {
+			reasons[label] = conflicts
+		}
+		sourcesByTarget[transformation.NestedStructure] = append(sourcesByTarget[transformation.NestedStructure], label)
+	}
+	for target, sources := range sourcesByTarget {
+		if len(sources) > 1 {
+			sort.Strings(sources)
+			for _, label := range sources {
+				reasons[label] = append(reasons[label], fmt.Sprintf("Target label '%s' is claimed by multiple labels: %s", target, strings.Join(sources, ", ")))
 			}
 		}
+	}
+	return reasons
+}
+
+// normalizedLookup finds existing labels the way Gmail displays names, ignoring surrounding whitespace
+func normalizedLookup(existingLabels map[string]*gmailAPI.Label) func(name string) (*gmailAPI.Label, bool) {
+	normalized := make(map[string]*gmailAPI.Label, len(existingLabels))
+	for name, label := range existingLabels {
+		normalized[gmail.NormalizeLabelName(name)] = label
+	}
+	return func(name string) (*gmailAPI.Label, bool) {
+		if label, exists := existingLabels[name]; exists {
+			return label, true
+		}
+		label, exists := normalized[gmail.NormalizeLabelName(name)]
+		return label, exists
+	}
+}
 
-		// Check if the target nested name already exists
-		if existingLabel, exists := a.client.LabelExists(transformation.NestedStructure); exists {
+// transformationConflicts lists the problems of a single transformation: existing parents or
+// target, and names Gmail would reject
+func transformationConflicts(transformation *LabelTransformation, lookup func(name string) (*gmailAPI.Label, bool)) []string {
+	var conflicts []string
+
+	// Check if any required parent names conflict with existing labels
+	for _, parentName := range transformation.RequiredParents {
+		if existingLabel, exists := lookup(parentName); exists {
+			conflicts = append(conflicts, fmt.Sprintf("Parent label '%s' already exists (ID: %s)", parentName, existingLabel.Id))
+		}
+	}
+
+	// Check if the target nested name already exists
+	if existingLabel, exists := lookup(transformation.NestedStructure); exists {
+		if StripsInboxPrefix(transformation) {
+			// The INBOX prefix hides that the stripped name is already taken
+			conflicts = append(conflicts, InboxStripConflict(transformation, existingLabel))
+		} else {
 			conflicts = append(conflicts, fmt.Sprintf("Target label '%s' already exists (ID: %s)", transformation.NestedStructure, existingLabel.Id))
 		}
 	}
 
+	// Check the nested name against Gmail's label limits
+	for _, problem := range ValidateTransformation(transformation) {
+		conflicts = append(conflicts, fmt.Sprintf("Label '%s' cannot be converted: %s", transformation.OriginalLabel, problem))
+	}
+
 	return conflicts
 }
+
+// InboxStripConflict explains a conflict caused by removing the INBOX prefix, e.g. INBOX.Work
+// becoming Work when a Work label already exists
+func InboxStripConflict(transformation *LabelTransformation, existing *gmailAPI.Label) string {
+	return fmt.Sprintf("Label '%s' becomes '%s' once the INBOX prefix is removed, but '%s' already exists (ID: %s); use --on-conflict merge to combine them", transformation.OriginalLabel, transformation.NestedStructure, existing.Name, existing.Id)
+}
+
+// FindAmbiguousLabels reports existing labels whose names differ only in surrounding whitespace
+// or case. Gmail's UI shows them identically, so renaming into them is unpredictable.
+func FindAmbiguousLabels(existingLabels map[string]*gmailAPI.Label) []string {
+	groups := make(map[string][]*gmailAPI.Label)
+	for name, label := range existingLabels {
+		key := strings.ToLower(gmail.NormalizeLabelName(name))
+		groups[key] = append(groups[key], label)
+	}
+
+	var warnings []string
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Name < group[j].Name
+		})
+		var names []string
+		for _, label := range group {
+			names = append(names, fmt.Sprintf("'%s' (ID: %s)", label.Name, label.Id))
+		}
+		warnings = append(warnings, fmt.Sprintf("Labels %s look identical in Gmail", strings.Join(names, " and ")))
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// FindNearDuplicates reports target and parent names that differ only in case from an existing label
+// or from another transformation's target. Gmail compares label names case-insensitively, so these
+// renames fail even though no exact conflict is reported.
+func (a *Analyzer) FindNearDuplicates(transformations map[string]*LabelTransformation, existingLabels map[string]*gmailAPI.Label) []string {
+	existingByLower := make(map[string][]string)
+	for name := range existingLabels {
+		existingByLower[strings.ToLower(name)] = append(existingByLower[strings.ToLower(name)], name)
+	}
+
+	targetsByLower := make(map[string][]*LabelTransformation)
+	for _, transformation := range transformations {
+		lower := strings.ToLower(transformation.NestedStructure)
+		targetsByLower[lower] = append(targetsByLower[lower], transformation)
+	}
+
+	seen := make(map[string]bool)
+	var warnings []string
+	add := func(warning string) {
+		if !seen[warning] {
+			seen[warning] = true
+			warnings = append(warnings, warning)
+		}
+	}
+
+	for _, transformation := range transformations {
+		names := append([]string{transformation.NestedStructure}, transformation.RequiredParents...)
+		for _, name := range names {
+			for _, existing := range existingByLower[strings.ToLower(name)] {
+				// Exact matches are conflicts, and a label matching its own old name is harmless
+				if existing != name && existing != transformation.OriginalLabel {
+					add(fmt.Sprintf("'%s' differs only in case from existing label '%s'", name, existing))
+				}
+			}
+		}
+
+		for _, other := range targetsByLower[strings.ToLower(transformation.NestedStructure)] {
+			if other.NestedStructure != transformation.NestedStructure {
+				first, second := transformation, other
+				if second.OriginalLabel < first.OriginalLabel {
+					first, second = second, first
+				}
+				add(fmt.Sprintf("'%s' (from %s) and '%s' (from %s) differ only in case", first.NestedStructure, first.OriginalLabel, second.NestedStructure, second.OriginalLabel))
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// FindDependencyWarnings reports parent paths that Gmail will create on its own during this run
+// although a skipped label would later be renamed to that same path. Renames run parents first,
+// so within a run every parent exists or is renamed into place before its children; a skipped
+// label, however, finds its target already taken when it is fixed later.
+func (a *Analyzer) FindDependencyWarnings(transformations map[string]*LabelTransformation, skipped []gmail.SkippedLabel) []string {
+	skippedByTarget := make(map[string]gmail.SkippedLabel)
+	for _, skip := range skipped {
+		if transformation := a.Parse(skip.Label.Name); transformation != nil {
+			skippedByTarget[transformation.NestedStructure] = skip
+		}
+	}
+
+	seen := make(map[string]bool)
+	var warnings []string
+	for _, transformation := range transformations {
+		for _, parent := range transformation.RequiredParents {
+			skip, owned := skippedByTarget[parent]
+			if !owned || seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			warnings = append(warnings, fmt.Sprintf("'%s' is a parent of %s and will be created during this run, but skipped label %s (%s) would also become '%s' and will conflict when fixed later (use --on-conflict merge to move its messages into it)", parent, transformation.OriginalLabel, skip.Label.Name, skip.Reason, parent))
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// FindCollisions reports nested names that more than one source label would be renamed to
+func FindCollisions(transformations map[string]*LabelTransformation) []string {
+	sourcesByTarget := make(map[string][]string)
+	for _, transformation := range transformations {
+		sourcesByTarget[transformation.NestedStructure] = append(sourcesByTarget[transformation.NestedStructure], transformation.OriginalLabel)
+	}
+
+	var targets []string
+	for target, sources := range sourcesByTarget {
+		if len(sources) > 1 {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+
+	var collisions []string
+	for _, target := range targets {
+		sources := sourcesByTarget[target]
+		sort.Strings(sources)
+		collisions = append(collisions, fmt.Sprintf("Target label '%s' is claimed by multiple labels: %s", target, strings.Join(sources, ", ")))
+	}
+	return collisions
+}
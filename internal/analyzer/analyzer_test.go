@@ -0,0 +1,24 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestCheckConflictsExplainsInboxStrip(t *testing.T) {
+	existing := map[string]*gmailAPI.Label{
+		"INBOX.Work": {Id: "inbox-work", Name: "INBOX.Work"},
+		"Work":       {Id: "work", Name: "Work"},
+	}
+	transformations := BuildHierarchyMap([]string{"INBOX.Work"})
+
+	conflicts := NewAnalyzer(nil).CheckConflicts(transformations, existing)
+	if len(conflicts) != 1 {
+		t.Fatalf("CheckConflicts = %v, want one conflict", conflicts)
+	}
+	if !strings.Contains(conflicts[0], "once the INBOX prefix is removed") || !strings.Contains(conflicts[0], "--on-conflict merge") {
+		t.Errorf("conflict %q does not explain the INBOX prefix or suggest merging", conflicts[0])
+	}
+}
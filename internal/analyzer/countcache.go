@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CountEntry is a cached message count and when it was fetched
+type CountEntry struct {
+	Count     int       `json:"count"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// CountCache keeps label message counts on disk between runs, so fix can reuse the counts an
+// analyze run just fetched. Entries older than the TTL are ignored.
+type CountCache struct {
+	Account string                `json:"account"`
+	Entries map[string]CountEntry `json:"entries"` // Keyed by label ID
+
+	path string
+	ttl  time.Duration
+	mu   sync.Mutex
+}
+
+// LoadCountCache reads the cache at path, starting empty if it is missing or unreadable
+func LoadCountCache(path string, ttl time.Duration) *CountCache {
+	cache := &CountCache{path: path, ttl: ttl}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, cache) // A corrupt cache is simply rebuilt
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]CountEntry)
+	}
+	return cache
+}
+
+// SetAccount drops every entry when the cache was written for a different account, since
+// label IDs are only unique within one mailbox
+func (c *CountCache) SetAccount(account string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Account != account {
+		c.Account = account
+		c.Entries = make(map[string]CountEntry)
+	}
+}
+
+// Get returns the cached count for a label if it is younger than the TTL
+func (c *CountCache) Get(labelID string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.Entries[labelID]
+	if !exists || time.Since(entry.FetchedAt) > c.ttl {
+		return 0, false
+	}
+	return entry.Count, true
+}
+
+// Put stores a freshly fetched count
+func (c *CountCache) Put(labelID string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[labelID] = CountEntry{Count: count, FetchedAt: time.Now()}
+}
+
+// Invalidate forgets a label's count, e.g. after it was renamed, merged or deleted
+func (c *CountCache) Invalidate(labelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.Entries, labelID)
+}
+
+// Save writes the cache back to disk, leaving out expired entries
+func (c *CountCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.Entries {
+		if time.Since(entry.FetchedAt) > c.ttl {
+			delete(c.Entries, id)
+		}
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode count cache: %v", err)
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write count cache %s: %v", c.path, err)
+	}
+	return nil
+}
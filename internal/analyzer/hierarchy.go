@@ -1,7 +1,11 @@
 package analyzer
 
 import (
+	"gmail-label-fixer/internal/gmail"
+	"slices"
 	"strings"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
 )
 
 type LabelTransformation struct {
@@ -13,38 +17,192 @@ type LabelTransformation struct {
 	RequiredParents []string
 }
 
+// ParseLabelHierarchy converts a period-separated label name into its nested form.
+// All consecutive leading INBOX segments are stripped, case-insensitively, since IMAP
+// imports nest folders under INBOX:
+//
+//	INBOX.INBOX.A.B → A/B
+//	inbox.x         → x (a root label with no parents)
+//	INBOX.INBOX     → nil (nothing is left once the prefix is removed)
+//
+// A label literally named INBOX has no period and is never transformed itself, while
+// its children such as INBOX.Work become root labels like Work.
 func ParseLabelHierarchy(labelName string) *LabelTransformation {
-	parts := strings.Split(labelName, ".")
+	return ParseLabelHierarchyWithSeparators(labelName, []string{"."})
+}
+
+// ParseLabelHierarchyWithSeparators converts a label name like ParseLabelHierarchy, splitting it
+// at any of separators, so Work.Projects/Acme becomes Work/Projects/Acme with "." and "/"
+func ParseLabelHierarchyWithSeparators(labelName string, separators []string) *LabelTransformation {
+	parts := splitAny(labelName, separators)
 	if len(parts) <= 1 {
 		return nil // Not a period-separated label
 	}
+	if slices.Contains(parts, "") {
+		return nil // A. or A..B would nest into A/ or A//B, which Gmail rejects
+	}
 
-	// Special handling for INBOX prefix - remove it entirely
+	// Special handling for INBOX prefix - remove every leading INBOX segment
 	finalParts := parts
-	if len(parts) > 1 && strings.ToUpper(parts[0]) == "INBOX" {
-		finalParts = parts[1:] // Remove INBOX prefix
-
-		// If after removing INBOX there's only one part left, it becomes a root label
-		if len(finalParts) == 1 {
-			transformation := &LabelTransformation{
-				OriginalLabel:   labelName,
-				HierarchyParts:  finalParts,
-				NestedStructure: finalParts[0], // Just the label name, no hierarchy
-				RequiredParents: []string{},    // No parents needed
+	for len(finalParts) > 0 && strings.EqualFold(finalParts[0], "INBOX") {
+		finalParts = finalParts[1:]
+	}
+
+	if len(finalParts) == 0 {
+		return nil // Nothing but INBOX segments, no label to create
+	}
+
+	return newTransformation(labelName, finalParts)
+}
+
+// splitAny splits s at every occurrence of any of separators, keeping empty segments
+func splitAny(s string, separators []string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); {
+		matched := ""
+		for _, separator := range separators {
+			if strings.HasPrefix(s[i:], separator) {
+				matched = separator
+				break
 			}
-			return transformation
 		}
+		if matched == "" {
+			i++
+			continue
+		}
+		parts = append(parts, s[start:i])
+		i += len(matched)
+		start = i
 	}
+	return append(parts, s[start:])
+}
+
+// StripsInboxPrefix reports whether the transformation dropped leading INBOX segments
+func StripsInboxPrefix(transformation *LabelTransformation) bool {
+	first, _, _ := strings.Cut(transformation.OriginalLabel, ".")
+	return strings.EqualFold(first, "INBOX")
+}
 
+// ParseOptions adjusts how label names are converted
+type ParseOptions struct {
+	FlattenTop int  // Leading segments dropped from every nested name, after INBOX stripping
+	Reverse    bool // Convert nested labels back to period-separated names instead
+	// SlashReplacement replaces any / inside a segment, which Gmail would read as nesting.
+	// Empty leaves them in place for ValidateTransformation to reject.
+	SlashReplacement string
+	// MixedSeparators treats / like . when splitting, for half-migrated labels such as
+	// Work.Projects/Acme. SlashReplacement then has nothing left to replace.
+	MixedSeparators bool
+	// TrimSegments removes leading and trailing whitespace from every segment, e.g. the stray
+	// spaces IMAP imports leave in Work. Projects .X
+	TrimSegments bool
+	// Renames maps original label names to explicit nested targets, used as-is instead of the
+	// derived name (see LoadRenameMap)
+	Renames map[string]string
+}
+
+// SourceSeparator returns the separator between segments of the labels being converted
+func (opts ParseOptions) SourceSeparator() string {
+	if opts.Reverse {
+		return "/"
+	}
+	return "."
+}
+
+// SourceSeparators returns every separator between segments of the labels being converted,
+// including / for half-migrated labels under MixedSeparators
+func (opts ParseOptions) SourceSeparators() []string {
+	if opts.MixedSeparators && !opts.Reverse {
+		return []string{".", "/"}
+	}
+	return []string{opts.SourceSeparator()}
+}
+
+// ParseNestedHierarchy converts a nested label name into its period-separated form, e.g.
+// A/B/C → A.B.C. Period-separated names are flat, so no parent labels are required.
+func ParseNestedHierarchy(labelName string) *LabelTransformation {
+	parts := strings.Split(labelName, "/")
+	if len(parts) <= 1 {
+		return nil // Not a nested label
+	}
+	if slices.Contains(parts, "") {
+		return nil // Empty segments would leave stray periods in the name
+	}
+
+	return &LabelTransformation{
+		OriginalLabel:   labelName,
+		HierarchyParts:  parts,
+		NestedStructure: strings.Join(parts, "."),
+		RequiredParents: []string{},
+	}
+}
+
+// ParseLabelHierarchyWithOptions converts a label name like ParseLabelHierarchy, then applies opts.
+// Slashes inside segments are replaced before any segments are flattened away.
+// Flattening away every segment leaves an empty nested name, which ValidateTransformation rejects.
+// A label listed in opts.Renames gets its mapped target instead.
+func ParseLabelHierarchyWithOptions(labelName string, opts ParseOptions) *LabelTransformation {
+	if opts.Reverse {
+		return ParseNestedHierarchy(labelName)
+	}
+	if target, mapped := opts.Renames[labelName]; mapped {
+		return newTransformation(labelName, strings.Split(target, "/"))
+	}
+
+	transformation := ParseLabelHierarchyWithSeparators(labelName, opts.SourceSeparators())
+	if transformation != nil && transformation.NestedStructure == labelName {
+		return nil // Already nested with / only, nothing to normalize
+	}
+	if transformation == nil || (opts.FlattenTop <= 0 && opts.SlashReplacement == "" && !opts.TrimSegments) {
+		return transformation
+	}
+
+	parts := transformation.HierarchyParts
+	if opts.SlashReplacement != "" {
+		sanitized := make([]string, len(parts))
+		for i, part := range parts {
+			sanitized[i] = strings.ReplaceAll(part, "/", opts.SlashReplacement)
+		}
+		parts = sanitized
+	}
+	if opts.TrimSegments {
+		trimmed := make([]string, len(parts))
+		for i, part := range parts {
+			trimmed[i] = strings.TrimSpace(part)
+		}
+		parts = trimmed
+	}
+	if opts.FlattenTop >= len(parts) {
+		parts = nil
+	} else if opts.FlattenTop > 0 {
+		parts = parts[opts.FlattenTop:]
+	}
+	return newTransformation(labelName, parts)
+}
+
+// HasPaddedSegments reports whether any segment of a label name starts or ends with whitespace
+func HasPaddedSegments(labelName, separator string) bool {
+	for _, segment := range strings.Split(labelName, separator) {
+		if strings.TrimSpace(segment) != segment {
+			return true
+		}
+	}
+	return false
+}
+
+// newTransformation builds the transformation of labelName into the nested path of parts
+func newTransformation(labelName string, parts []string) *LabelTransformation {
 	transformation := &LabelTransformation{
 		OriginalLabel:   labelName,
-		HierarchyParts:  finalParts,
-		NestedStructure: strings.Join(finalParts, "/"),
+		HierarchyParts:  parts,
+		NestedStructure: strings.Join(parts, "/"),
+		RequiredParents: []string{}, // A single part is a root label with no parents
 	}
 
 	// Build required parent labels (excluding any INBOX prefix)
-	for i := 1; i < len(finalParts); i++ {
-		parentPath := strings.Join(finalParts[:i], "/")
+	for i := 1; i < len(parts); i++ {
+		parentPath := strings.Join(parts[:i], "/")
 		transformation.RequiredParents = append(transformation.RequiredParents, parentPath)
 	}
 
@@ -79,3 +237,23 @@ func GetAllRequiredParents(transformations map[string]*LabelTransformation) []st
 
 	return parents
 }
+
+// MissingParents returns the required parents of a transformation that neither exist yet nor
+// will be produced by renaming another label in the batch. Gmail creates these automatically.
+// Names are matched like NewParentLabels does, ignoring surrounding whitespace.
+func MissingParents(transformation *LabelTransformation, existingLabels map[string]*gmailAPI.Label, transformations map[string]*LabelTransformation) []string {
+	lookup := normalizedLookup(existingLabels)
+	targets := make(map[string]bool, len(transformations))
+	for _, other := range transformations {
+		targets[gmail.NormalizeLabelName(other.NestedStructure)] = true
+	}
+
+	var missing []string
+	for _, parent := range transformation.RequiredParents {
+		if _, exists := lookup(parent); exists || targets[gmail.NormalizeLabelName(parent)] {
+			continue
+		}
+		missing = append(missing, parent)
+	}
+	return missing
+}
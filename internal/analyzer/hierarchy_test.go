@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"slices"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestMissingParentsMatchesNewParentLabels(t *testing.T) {
+	existing := map[string]*gmailAPI.Label{"Work ": {Id: "work", Name: "Work "}}
+	transformations := BuildHierarchyMap([]string{"Work.Acme", "Home.Bills"})
+
+	var missing []string
+	for _, label := range []string{"Home.Bills", "Work.Acme"} {
+		missing = append(missing, MissingParents(transformations[label], existing, transformations)...)
+	}
+	newParents := NewParentLabels(transformations, existing)
+
+	if len(missing) != 1 || missing[0] != "Home" {
+		t.Errorf("MissingParents = %v, want [Home]", missing)
+	}
+	if len(newParents) != len(missing) || newParents[0] != missing[0] {
+		t.Errorf("NewParentLabels = %v, MissingParents = %v, want them to agree", newParents, missing)
+	}
+}
+
+func TestParseLabelHierarchyStripsInboxPrefix(t *testing.T) {
+	tests := []struct {
+		label       string
+		wantNested  string // Empty when the label is not transformed
+		wantParents []string
+	}{
+		{"INBOX.INBOX.A.B", "A/B", []string{"A"}},
+		{"inbox.x", "x", []string{}},
+		{"Inbox.Work.Stuff", "Work/Stuff", []string{"Work"}},
+		{"INBOX", "", nil},
+		{"INBOX.Work", "Work", []string{}},
+		{"INBOX.INBOX", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			transformation := ParseLabelHierarchy(tt.label)
+			if tt.wantNested == "" {
+				if transformation != nil {
+					t.Errorf("ParseLabelHierarchy(%q) = %q, want nil", tt.label, transformation.NestedStructure)
+				}
+				return
+			}
+			if transformation == nil {
+				t.Fatalf("ParseLabelHierarchy(%q) = nil, want %q", tt.label, tt.wantNested)
+			}
+			if transformation.NestedStructure != tt.wantNested || !slices.Equal(transformation.RequiredParents, tt.wantParents) {
+				t.Errorf("ParseLabelHierarchy(%q) = %q with parents %v, want %q with parents %v",
+					tt.label, transformation.NestedStructure, transformation.RequiredParents, tt.wantNested, tt.wantParents)
+			}
+		})
+	}
+}
+
+func TestParseLabelHierarchyTrimSegments(t *testing.T) {
+	tests := []struct {
+		label      string
+		wantNested string
+		wantPadded bool
+	}{
+		{"Work. Projects .X", "Work/Projects/X", true},
+		{" Work.Projects ", "Work/Projects", true},
+		{"My Work.Big Projects", "My Work/Big Projects", false},
+		{"Work.\tNotes", "Work/Notes", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			transformation := ParseLabelHierarchyWithOptions(tt.label, ParseOptions{TrimSegments: true})
+			if transformation == nil || transformation.NestedStructure != tt.wantNested {
+				t.Errorf("ParseLabelHierarchyWithOptions(%q) = %+v, want %q", tt.label, transformation, tt.wantNested)
+			}
+			if padded := HasPaddedSegments(tt.label, "."); padded != tt.wantPadded {
+				t.Errorf("HasPaddedSegments(%q) = %v, want %v", tt.label, padded, tt.wantPadded)
+			}
+		})
+	}
+
+	if untrimmed := ParseLabelHierarchy("Work. Projects .X"); untrimmed.NestedStructure != "Work/ Projects /X" {
+		t.Errorf("without trimming got %q, want the segments kept verbatim", untrimmed.NestedStructure)
+	}
+}
+
+func TestParseLabelHierarchyMixedSeparators(t *testing.T) {
+	tests := []struct {
+		label      string
+		wantNested string // Empty when the label is not transformed
+	}{
+		{"Work.Projects/Acme", "Work/Projects/Acme"},
+		{"Work/Projects.Acme", "Work/Projects/Acme"},
+		{"A.B/C.D", "A/B/C/D"},
+		{"Work.Projects", "Work/Projects"},
+		{"Work/Projects", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			transformation := ParseLabelHierarchyWithOptions(tt.label, ParseOptions{MixedSeparators: true})
+			if tt.wantNested == "" {
+				if transformation != nil {
+					t.Errorf("ParseLabelHierarchyWithOptions(%q) = %q, want nil", tt.label, transformation.NestedStructure)
+				}
+				return
+			}
+			if transformation == nil || transformation.NestedStructure != tt.wantNested {
+				t.Errorf("ParseLabelHierarchyWithOptions(%q) = %+v, want %q", tt.label, transformation, tt.wantNested)
+			}
+		})
+	}
+}
+
+func TestParseLabelHierarchyRejectsEmptySegments(t *testing.T) {
+	for _, label := range []string{"A.", ".A", "A..B", "...", "."} {
+		if transformation := ParseLabelHierarchy(label); transformation != nil {
+			t.Errorf("ParseLabelHierarchy(%q) = %q, want nil", label, transformation.NestedStructure)
+		}
+	}
+}
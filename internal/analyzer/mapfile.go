@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadRenameMap reads explicit renames from a --map-file: a JSON object of original name to
+// target nested name, or, for a .csv file, rows of original name and target name
+func LoadRenameMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map file: %v", err)
+	}
+
+	renames := make(map[string]string)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		reader := csv.NewReader(strings.NewReader(string(data)))
+		reader.FieldsPerRecord = 2
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse map file %s: %v", path, err)
+		}
+		for _, row := range rows {
+			if _, duplicate := renames[row[0]]; duplicate {
+				return nil, fmt.Errorf("map file %s lists '%s' more than once", path, row[0])
+			}
+			renames[row[0]] = row[1]
+		}
+	} else if err := json.Unmarshal(data, &renames); err != nil {
+		return nil, fmt.Errorf("failed to parse map file %s: %v", path, err)
+	}
+
+	for original, target := range renames {
+		if strings.TrimSpace(target) == "" {
+			return nil, fmt.Errorf("map file %s gives '%s' an empty target", path, original)
+		}
+	}
+	return renames, nil
+}
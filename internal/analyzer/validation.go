@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Gmail label limits that a nested name must respect
+const (
+	MaxLabelNameLength    = 225 // Maximum length of a full label path, including separators
+	MaxLabelSegmentLength = 40  // Maximum length of a single path component
+)
+
+// ValidateTransformation returns the reasons a transformation's nested name would be rejected by Gmail
+func ValidateTransformation(transformation *LabelTransformation) []string {
+	var problems []string
+
+	if transformation.NestedStructure == "" {
+		return []string{"nested name is empty, every segment was removed"}
+	}
+
+	if slices.Contains(transformation.HierarchyParts, "") {
+		problems = append(problems, "nested name has an empty segment")
+	}
+
+	for _, segment := range transformation.HierarchyParts {
+		if strings.Contains(segment, "/") {
+			problems = append(problems, fmt.Sprintf("segment '%s' contains '/', which Gmail would read as another nesting level (use --sanitize to replace it)", segment))
+		}
+		if length := utf8.RuneCountInString(segment); length > MaxLabelSegmentLength {
+			problems = append(problems, fmt.Sprintf("segment '%s' is %d characters, exceeding the maximum of %d", segment, length, MaxLabelSegmentLength))
+		}
+	}
+
+	if length := utf8.RuneCountInString(transformation.NestedStructure); length > MaxLabelNameLength {
+		problems = append(problems, fmt.Sprintf("nested name is %d characters, exceeding the maximum of %d", length, MaxLabelNameLength))
+	}
+
+	return problems
+}
@@ -0,0 +1,29 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTransformationAllowsDeepNesting(t *testing.T) {
+	transformation := ParseLabelHierarchy(strings.Repeat("A.", 15) + "B")
+	if problems := ValidateTransformation(transformation); len(problems) != 0 {
+		t.Errorf("16 levels of nesting rejected: %v", problems)
+	}
+}
+
+func TestSegmentsWithEmbeddedSlashes(t *testing.T) {
+	rejected := ParseLabelHierarchy("Projects.A/B.Notes")
+	problems := ValidateTransformation(rejected)
+	if len(problems) != 1 || !strings.Contains(problems[0], "segment 'A/B' contains '/'") {
+		t.Errorf("ValidateTransformation(Projects.A/B.Notes) = %v, want the A/B segment rejected", problems)
+	}
+
+	sanitized := ParseLabelHierarchyWithOptions("Projects.A/B.Notes", ParseOptions{SlashReplacement: "-"})
+	if sanitized == nil || sanitized.NestedStructure != "Projects/A-B/Notes" {
+		t.Fatalf("sanitized transformation = %+v, want Projects/A-B/Notes", sanitized)
+	}
+	if problems := ValidateTransformation(sanitized); len(problems) != 0 {
+		t.Errorf("sanitized name rejected: %v", problems)
+	}
+}
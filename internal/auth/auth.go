@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -17,25 +21,87 @@ import (
 )
 
 const (
-	tokenFile = "token.json"
+	DefaultCredentialsFile = "credentials.json"
+	DefaultTokenFile       = "token.json"
 	// Loopback configuration for secure CLI OAuth flow
 	loopbackHost = "127.0.0.1"
 )
 
-func GetGmailService() (*gmail.Service, error) {
-	ctx := context.Background()
+// OAuth scopes commands authenticate with
+const (
+	ScopeModify   = gmail.GmailModifyScope   // Read and change labels, for commands that modify the mailbox
+	ScopeReadOnly = gmail.GmailReadonlyScope // Read-only access, enough for analyze and other reports
+)
+
+// RefreshTokenEnv names the environment variable holding a refresh token for non-interactive
+// sign-in, e.g. in CI. When set, the browser flow and the token file are skipped entirely.
+const RefreshTokenEnv = "GMAIL_FIXER_REFRESH_TOKEN"
+
+// output receives the interactive authentication messages, on stderr so a sign-in prompt is
+// seen even when stdout is redirected or status output is silenced
+var output io.Writer = os.Stderr
+
+// SetOutput changes where authentication status messages are written
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// oauthPort is the loopback port the sign-in redirect is received on (0 picks a free one)
+var oauthPort int
+
+// noBrowser skips launching a browser and prints the sign-in instructions instead
+var noBrowser bool
+
+// SetNoBrowser turns the browser launch off, for headless machines such as SSH sessions
+func SetNoBrowser(skip bool) {
+	noBrowser = skip
+}
+
+// looksHeadless reports whether no browser can likely be opened, e.g. over SSH or without a display
+func looksHeadless() bool {
+	if os.Getenv("SSH_CONNECTION") != "" {
+		return true
+	}
+	return runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+// SetOAuthPort fixes the loopback redirect port, for redirect URIs pre-registered with Google
+func SetOAuthPort(port int) {
+	oauthPort = port
+}
+
+// GetGmailService authenticates using the OAuth client in credPath, requesting scope and caching
+// the token at the ScopedTokenPath of tokenPath. Empty paths fall back to credentials.json and
+// token.json in the current directory, and an empty scope to gmail.modify.
+func GetGmailService(ctx context.Context, credPath, tokenPath, scope string) (*gmail.Service, error) {
 
-	b, err := os.ReadFile("credentials.json")
+	if credPath == "" {
+		credPath = DefaultCredentialsFile
+	}
+	if tokenPath == "" {
+		tokenPath = DefaultTokenFile
+	}
+	if scope == "" {
+		scope = ScopeModify
+	}
+	tokenPath = scopedTokenFile(tokenPath, scope)
+
+	b, err := os.ReadFile(credPath)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read client secret file: %v.\n\nPlease ensure you have:\n1. Created OAuth 2.0 credentials in Google Cloud Console\n2. Downloaded the credentials JSON file\n3. Renamed it to 'credentials.json' in the current directory", err)
+		return nil, fmt.Errorf("unable to read client secret file: %v.\n\nPlease ensure you have:\n1. Created OAuth 2.0 credentials in Google Cloud Console\n2. Downloaded the credentials JSON file\n3. Saved it as '%s' (or pointed --credentials at it)", err, credPath)
 	}
 
-	config, err := google.ConfigFromJSON(b, gmail.GmailModifyScope)
+	config, err := google.ConfigFromJSON(b, scope)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
 
-	client, err := getClient(config)
+	var client *http.Client
+	if refreshToken := os.Getenv(RefreshTokenEnv); refreshToken != "" {
+		client, err = refreshTokenClient(ctx, config, refreshToken)
+	} else {
+		client, err = getClient(ctx, config, tokenPath)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -48,25 +114,93 @@ func GetGmailService() (*gmail.Service, error) {
 	return srv, nil
 }
 
-func getClient(config *oauth2.Config) (*http.Client, error) {
-	tokFile := tokenFile
+// ScopedTokenPath returns where the token for scope is cached. The gmail.modify token keeps
+// tokenPath itself; narrower scopes get their own file, e.g. token-readonly.json, so the tokens
+// don't overwrite each other.
+func ScopedTokenPath(tokenPath, scope string) string {
+	if scope != ScopeReadOnly {
+		return tokenPath
+	}
+	ext := filepath.Ext(tokenPath)
+	return strings.TrimSuffix(tokenPath, ext) + "-readonly" + ext
+}
+
+// scopedTokenFile picks the token file to use for scope. A read-only run without its own token
+// reuses an existing gmail.modify token, which covers it, instead of asking to sign in again.
+func scopedTokenFile(tokenPath, scope string) string {
+	scoped := ScopedTokenPath(tokenPath, scope)
+	if scoped == tokenPath {
+		return tokenPath
+	}
+	if _, err := os.Stat(scoped); err != nil {
+		if _, err := os.Stat(tokenPath); err == nil {
+			return tokenPath
+		}
+	}
+	return scoped
+}
+
+// refreshTokenClient mints access tokens from a long-lived refresh token. One token is minted
+// up front, so a revoked or mistyped refresh token fails here rather than on the first API call.
+func refreshTokenClient(ctx context.Context, config *oauth2.Config, refreshToken string) (*http.Client, error) {
+	source := config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken})
+	tok, err := source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("the refresh token in %s was rejected: %v", RefreshTokenEnv, err)
+	}
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(tok, source)), nil
+}
+
+func getClient(ctx context.Context, config *oauth2.Config, tokFile string) (*http.Client, error) {
 	tok, err := tokenFromFile(tokFile)
 	if err != nil {
 		// Need to obtain new token interactively
-		if tok, err = getTokenFromWeb(config); err != nil {
+		if tok, err = getTokenFromWeb(ctx, config); err != nil {
 			return nil, err
 		}
 		if err := saveToken(tokFile, tok); err != nil {
 			return nil, fmt.Errorf("failed to save token: %w", err)
 		}
 	}
-	return config.Client(context.Background(), tok), nil
+
+	// Persist refreshed tokens so later runs don't fall back to the browser flow
+	source := &persistingTokenSource{
+		base: config.TokenSource(ctx, tok),
+		path: tokFile,
+		last: tok,
+	}
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(tok, source)), nil
 }
 
-func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
-	// Find an available port for the loopback server
-	listener, err := net.Listen("tcp", loopbackHost+":0")
+// persistingTokenSource saves the token to disk whenever the underlying source refreshes it
+type persistingTokenSource struct {
+	base oauth2.TokenSource
+	path string
+	last *oauth2.Token
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
 	if err != nil {
+		return nil, err
+	}
+
+	if s.last == nil || tok.AccessToken != s.last.AccessToken || tok.RefreshToken != s.last.RefreshToken {
+		if err := writeToken(s.path, tok); err != nil {
+			fmt.Fprintf(output, "⚠️  Warning: Could not save refreshed token to %s: %v\n", s.path, err)
+		}
+		s.last = tok
+	}
+	return tok, nil
+}
+
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	// Find an available port for the loopback server, unless one was requested
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", loopbackHost, oauthPort))
+	if err != nil {
+		if oauthPort != 0 {
+			return nil, fmt.Errorf("unable to listen on --oauth-port %d, is it already in use? %v", oauthPort, err)
+		}
 		return nil, fmt.Errorf("unable to create loopback server: %v", err)
 	}
 	port := listener.Addr().(*net.TCPAddr).Port
@@ -137,41 +271,58 @@ func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
 	// Generate authorization URL
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
-	fmt.Printf("\n🔐 Gmail Authentication Required\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("🌐 Opening browser for secure authentication...\n")
-	fmt.Printf("   URL: %s\n", authURL)
-	fmt.Printf("\n💡 This will open your browser and redirect back to this application\n")
-	fmt.Printf("   securely. No manual code copying required!\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Fprintf(output, "\n🔐 Gmail Authentication Required\n")
+	fmt.Fprintf(output, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	if noBrowser {
+		fmt.Fprintf(output, "🌐 Open this URL in a browser to sign in:\n\n")
+		fmt.Fprintf(output, "   %s\n\n", authURL)
+		fmt.Fprintf(output, "💡 Google redirects back to 127.0.0.1:%d on the machine running the browser.\n", port)
+		fmt.Fprintf(output, "   On a remote machine, forward that port over SSH first:\n")
+		fmt.Fprintf(output, "   ssh -L %d:127.0.0.1:%d <this-host>\n", port, port)
+		fmt.Fprintf(output, "   (use --oauth-port to keep the port the same between runs)\n")
+	} else {
+		fmt.Fprintf(output, "🌐 Opening browser for secure authentication...\n")
+		fmt.Fprintf(output, "   URL: %s\n", authURL)
+		fmt.Fprintf(output, "\n💡 This will open your browser and redirect back to this application\n")
+		fmt.Fprintf(output, "   securely. No manual code copying required!\n")
+		if looksHeadless() {
+			fmt.Fprintf(output, "\n⚠️  This looks like a headless or SSH session, so no browser may open.\n")
+			fmt.Fprintf(output, "   Re-run with --no-browser for instructions on signing in from another machine.\n")
+		}
+	}
+	fmt.Fprintf(output, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 
-	// Open browser automatically
-	openBrowser(authURL)
+	if !noBrowser {
+		openBrowser(authURL)
+	}
 
 	// Wait for authorization response or timeout
 	var code string
 	select {
 	case code = <-codeCh:
-		fmt.Printf("✅ Authorization received!\n")
+		fmt.Fprintf(output, "✅ Authorization received!\n")
 	case err := <-errCh:
 		server.Shutdown(context.Background())
 		return nil, fmt.Errorf("authorization failed: %w", err)
 	case <-time.After(5 * time.Minute):
 		server.Shutdown(context.Background())
 		return nil, fmt.Errorf("authorization timed out after 5 minutes")
+	case <-ctx.Done():
+		server.Shutdown(context.Background())
+		return nil, fmt.Errorf("authorization cancelled: %w", ctx.Err())
 	}
 
 	// Shutdown server gracefully
 	server.Shutdown(context.Background())
 
 	// Exchange authorization code for token
-	fmt.Printf("🔄 Exchanging authorization code for access token...\n")
-	token, err := config.Exchange(context.Background(), code)
+	fmt.Fprintf(output, "🔄 Exchanging authorization code for access token...\n")
+	token, err := config.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve token: %v\n\n💡 Make sure your OAuth client is configured as 'Desktop application':\n   https://console.cloud.google.com/apis/credentials", err)
 	}
 
-	fmt.Printf("✅ Authentication successful!\n\n")
+	fmt.Fprintf(output, "✅ Authentication successful!\n\n")
 	return token, nil
 }
 
@@ -181,15 +332,16 @@ func openBrowser(url string) {
 	var args []string
 
 	switch {
+	case runtime.GOOS == "windows":
+		// start's first quoted argument is the window title, hence the empty one
+		cmd = "cmd"
+		args = []string{"/c", "start", "", escapeCmdArg(url)}
 	case commandExists("open"): // macOS
 		cmd = "open"
 		args = []string{url}
 	case commandExists("xdg-open"): // Linux
 		cmd = "xdg-open"
 		args = []string{url}
-	case commandExists("rundll32"): // Windows
-		cmd = "rundll32"
-		args = []string{"url.dll,FileProtocolHandler", url}
 	default:
 		return // Can't open browser
 	}
@@ -198,6 +350,21 @@ func openBrowser(url string) {
 	go func() { _ = exec.Command(cmd, args...).Start() }()
 }
 
+// escapeCmdArg escapes the characters cmd.exe treats specially, so the & between an OAuth URL's
+// query parameters doesn't end the command and truncate the address. This stands in for quoting
+// the URL as start "" "<url>": Go passes arguments with its own quoting rules, which cmd.exe does
+// not follow, while a caret-escaped argument needs no quotes at all.
+func escapeCmdArg(arg string) string {
+	var b strings.Builder
+	for _, r := range arg {
+		if strings.ContainsRune("^&|<>()", r) {
+			b.WriteRune('^')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 func commandExists(cmd string) bool {
 	_, err := exec.LookPath(cmd)
 	return err == nil
@@ -215,8 +382,12 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 }
 
 func saveToken(path string, token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", path)
+	fmt.Fprintf(output, "Saving credential file to: %s\n", path)
+	return writeToken(path, token)
+}
 
+// writeToken stores the token at path with owner-only permissions
+func writeToken(path string, token *oauth2.Token) error {
 	// Remove existing file first to ensure proper permissions
 	_ = os.Remove(path)
 
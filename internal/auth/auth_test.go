@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// sequenceTokenSource hands out its tokens in order, repeating the last one
+type sequenceTokenSource struct {
+	tokens []*oauth2.Token
+}
+
+func (s *sequenceTokenSource) Token() (*oauth2.Token, error) {
+	tok := s.tokens[0]
+	if len(s.tokens) > 1 {
+		s.tokens = s.tokens[1:]
+	}
+	return tok, nil
+}
+
+func TestPersistingTokenSourceSavesRefreshedToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	original := &oauth2.Token{AccessToken: "old", RefreshToken: "refresh"}
+	refreshed := &oauth2.Token{AccessToken: "new", RefreshToken: "refresh"}
+	source := &persistingTokenSource{
+		base: &sequenceTokenSource{tokens: []*oauth2.Token{original, refreshed}},
+		path: path,
+		last: original,
+	}
+
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if _, err := tokenFromFile(path); err == nil {
+		t.Errorf("unchanged token was written to %s", path)
+	}
+
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	saved, err := tokenFromFile(path)
+	if err != nil {
+		t.Fatalf("reading saved token: %v", err)
+	}
+	if saved.AccessToken != "new" || saved.RefreshToken != "refresh" {
+		t.Errorf("saved token = %+v, want the refreshed one", saved)
+	}
+}
+
+func TestEscapeCmdArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{
+			"https://accounts.google.com/o/oauth2/auth?access_type=offline&client_id=abc&redirect_uri=http%3A%2F%2F127.0.0.1%3A8080%2Fcallback&response_type=code&scope=gmail&state=xyz",
+			"https://accounts.google.com/o/oauth2/auth?access_type=offline^&client_id=abc^&redirect_uri=http%3A%2F%2F127.0.0.1%3A8080%2Fcallback^&response_type=code^&scope=gmail^&state=xyz",
+		},
+		{"https://example.com/?a=(1)&b=2^3", "https://example.com/?a=^(1^)^&b=2^^3"},
+		{"https://example.com/?q=a|b<c>d", "https://example.com/?q=a^|b^<c^>d"},
+		{"https://example.com/plain", "https://example.com/plain"},
+	}
+	for _, tt := range tests {
+		if got := escapeCmdArg(tt.arg); got != tt.want {
+			t.Errorf("escapeCmdArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
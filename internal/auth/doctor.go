@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo" // Reports the scopes granted to an access token
+)
+
+// Check is one item of the setup checklist produced by Diagnose
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Diagnose checks the credentials and cached token without ever starting the browser flow.
+// Later checks are skipped once an earlier one they depend on fails.
+func Diagnose(ctx context.Context, credPath, tokenPath string) []Check {
+	if credPath == "" {
+		credPath = DefaultCredentialsFile
+	}
+	if tokenPath == "" {
+		tokenPath = DefaultTokenFile
+	}
+
+	var checks []Check
+	fail := func(name, format string, args ...interface{}) []Check {
+		return append(checks, Check{Name: name, Detail: fmt.Sprintf(format, args...)})
+	}
+	pass := func(name, format string, args ...interface{}) {
+		checks = append(checks, Check{Name: name, OK: true, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	b, err := os.ReadFile(credPath)
+	if err != nil {
+		return fail("Credentials file", "unable to read %s: %v", credPath, err)
+	}
+	config, err := google.ConfigFromJSON(b, gmail.GmailModifyScope)
+	if err != nil {
+		return fail("Credentials file", "unable to parse %s: %v", credPath, err)
+	}
+	pass("Credentials file", "%s parses", credPath)
+
+	// Desktop app clients are stored under "installed", web clients under "web"
+	var clientTypes map[string]json.RawMessage
+	_ = json.Unmarshal(b, &clientTypes)
+	if _, ok := clientTypes["installed"]; ok {
+		pass("OAuth client type", "Desktop application")
+	} else {
+		checks = append(checks, Check{Name: "OAuth client type", Detail: "not a Desktop application client; create one at https://console.cloud.google.com/apis/credentials"})
+	}
+
+	tok, err := tokenFromFile(tokenPath)
+	if err != nil {
+		return fail("Token", "no usable token at %s (%v); run any command to sign in", tokenPath, err)
+	}
+	switch {
+	case tok.RefreshToken == "":
+		checks = append(checks, Check{Name: "Token", Detail: fmt.Sprintf("%s has no refresh token; delete it and sign in again", tokenPath)})
+	case !tok.Expiry.IsZero() && tok.Expiry.Before(time.Now()):
+		pass("Token", "%s present, access token expired %s ago and will be refreshed", tokenPath, time.Since(tok.Expiry).Round(time.Second))
+	default:
+		pass("Token", "%s present", tokenPath)
+	}
+
+	source := config.TokenSource(ctx, tok)
+	fresh, err := source.Token()
+	if err != nil {
+		return fail("Token refresh", "%v; delete %s and sign in again", err, tokenPath)
+	}
+	if fresh.AccessToken != tok.AccessToken {
+		if err := writeToken(tokenPath, fresh); err != nil {
+			return fail("Token refresh", "refreshed but could not save %s: %v", tokenPath, err)
+		}
+		pass("Token refresh", "refreshed and saved")
+	}
+
+	if scopes, err := grantedScopes(ctx, fresh.AccessToken); err != nil {
+		checks = append(checks, Check{Name: "Granted scopes", Detail: fmt.Sprintf("could not look up scopes: %v", err)})
+	} else if !strings.Contains(" "+scopes+" ", " "+gmail.GmailModifyScope+" ") {
+		checks = append(checks, Check{Name: "Granted scopes", Detail: fmt.Sprintf("%s is missing (granted: %s); delete %s and sign in again", gmail.GmailModifyScope, scopes, tokenPath)})
+	} else {
+		pass("Granted scopes", "includes gmail.modify")
+	}
+
+	srv, err := gmail.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(fresh))))
+	if err != nil {
+		return fail("Gmail API access", "unable to create Gmail client: %v", err)
+	}
+	response, err := srv.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return fail("Gmail API access", "labels.list failed: %v", err)
+	}
+	pass("Gmail API access", "labels.list returned %d labels", len(response.Labels))
+
+	return checks
+}
+
+// grantedScopes asks Google's tokeninfo endpoint which scopes an access token carries
+func grantedScopes(ctx context.Context, accessToken string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?access_token="+url.QueryEscape(accessToken), nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("tokeninfo returned %s", response.Status)
+	}
+
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("unable to parse tokeninfo response: %v", err)
+	}
+	return info.Scope, nil
+}
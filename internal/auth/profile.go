@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// unsafeProfileChars matches characters that are not allowed in profile file names
+var unsafeProfileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// SanitizeProfile reduces a profile name to characters that are safe in a file name
+func SanitizeProfile(profile string) string {
+	return strings.Trim(unsafeProfileChars.ReplaceAllString(profile, "-"), "-")
+}
+
+// ProfilePaths returns the credentials and token files for a named profile.
+// An empty profile keeps the original credentials.json and token.json names. A profile-specific
+// credentials file (credentials-<profile>.json) is used when present, otherwise the shared one.
+func ProfilePaths(profile string) (credPath, tokenPath string, err error) {
+	if profile == "" {
+		return DefaultCredentialsFile, DefaultTokenFile, nil
+	}
+
+	name := SanitizeProfile(profile)
+	if name == "" {
+		return "", "", fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	tokenPath = fmt.Sprintf("token-%s.json", name)
+	credPath = fmt.Sprintf("credentials-%s.json", name)
+	if _, err := os.Stat(credPath); err != nil {
+		credPath = DefaultCredentialsFile
+	}
+	return credPath, tokenPath, nil
+}
@@ -0,0 +1,35 @@
+package gmail
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// WithCallLog writes a line per Gmail API call to w, with its method, target and elapsed time
+func WithCallLog(w io.Writer) Option {
+	return func(c *Client) {
+		c.callLog = w
+	}
+}
+
+// call charges units against the quota budget, then makes the API call through do, logging it
+// when a call log is configured
+func (c *Client) call(method, target string, units int, do func() error) error {
+	if err := c.spend(units); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := do()
+	if c.callLog != nil {
+		outcome := ""
+		if err != nil {
+			outcome = fmt.Sprintf(": %v", err)
+		}
+		c.callLogMu.Lock()
+		fmt.Fprintf(c.callLog, "   🔎 %s %s (%v)%s\n", method, target, time.Since(start).Round(time.Millisecond), outcome)
+		c.callLogMu.Unlock()
+	}
+	return err
+}
@@ -1,17 +1,27 @@
 package gmail
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/api/gmail/v1"
 )
 
 const (
 	userID = "me" // Gmail API user identifier for authenticated user
+
+	maxBatchModifyIDs = 1000 // Most message IDs Users.Messages.BatchModify accepts per call
 )
 
-// System labels that should be skipped during label fixing
+// Default system labels that should be skipped during label fixing
 var skipLabels = map[string]bool{
 	"INBOX.Trash":         true,
 	"INBOX.Sent":          true,
@@ -21,80 +31,389 @@ var skipLabels = map[string]bool{
 	"Inbox.Sent Messages": true,
 }
 
-// shouldSkipLabel checks if a label should be skipped during processing
-func shouldSkipLabel(labelName string) bool {
-	return skipLabels[labelName]
+// Prefixes of the nested labels Gmail manages itself, never converted by WithReverse
+var reservedNestedPrefixes = []string{"[Gmail]/", "[Imap]/"}
+
+// shouldSkipLabel checks if a label is a system label that should be skipped during processing
+func (c *Client) shouldSkipLabel(labelName string) bool {
+	if c.reverse {
+		for _, prefix := range reservedNestedPrefixes {
+			if strings.HasPrefix(labelName, prefix) {
+				return true
+			}
+		}
+	}
+	return skipLabels[labelName] || c.systemLabels[labelName]
+}
+
+// separator returns the segment separator of the labels this client treats as processable
+func (c *Client) separator() string {
+	if c.reverse {
+		return "/"
+	}
+	return "."
+}
+
+// targetSeparator returns the separator processable labels are converted to
+func (c *Client) targetSeparator() string {
+	if c.reverse {
+		return "."
+	}
+	return "/"
+}
+
+// hasEmptySegment reports whether splitting a label name at the separator leaves an empty
+// segment, counting the target separator too when it is allowed in names
+func (c *Client) hasEmptySegment(name string) bool {
+	if c.allowSlashes {
+		name = strings.ReplaceAll(name, c.targetSeparator(), c.separator())
+	}
+	return slices.Contains(strings.Split(name, c.separator()), "")
+}
+
+// nestedSkipReason explains why a label using the target separator is left alone
+func (c *Client) nestedSkipReason() string {
+	if c.reverse {
+		return SkipReasonPeriods
+	}
+	return SkipReasonNested
 }
 
+// Reasons reported for labels that are excluded from processing
+const (
+	SkipReasonSystem   = "system label"
+	SkipReasonFiltered = "does not match --label-filter"
+	SkipReasonExcluded = "excluded by --skip"
+	SkipReasonShallow  = "fewer segments than --min-segments"
+	SkipReasonHidden   = "hidden label (use --include-hidden)"
+	SkipReasonNested   = "already nested"
+	SkipReasonPeriods  = "already contains periods"
+	SkipReasonPrefix   = "matches --skip-prefix"
+	SkipReasonInactive = "no messages within --active-since"
+	SkipReasonEmpty    = "empty segment from a leading, trailing or doubled separator"
+)
+
+// LabelService is the set of label operations the analyzer and operations depend on.
+// Client implements it against the Gmail API; the operations tests use an in-memory fake.
+type LabelService interface {
+	GetAllLabels(ctx context.Context) ([]*gmail.Label, error)
+	GetLabelMessageCount(ctx context.Context, labelID string) (int, error)
+	GetThreadCountWithLabel(ctx context.Context, labelID string) (int, error)
+	CreateLabelWithVisibility(ctx context.Context, name, labelListVisibility, messageListVisibility string) (*gmail.Label, error)
+	CreateLabelLike(ctx context.Context, name string, template *gmail.Label) (*gmail.Label, error)
+	RenameLabel(ctx context.Context, labelID, newName string) (*gmail.Label, error)
+	RenameLabelIfMatches(ctx context.Context, labelID, expectedCurrentName, newName string) (*gmail.Label, error)
+	DeleteLabel(ctx context.Context, labelID string) error
+	GetMessagesWithLabel(ctx context.Context, labelID string) ([]string, error)
+	GetNewestMessageDate(ctx context.Context, labelID string) (time.Time, error)
+	ModifyMessageLabels(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error
+	BatchModifyMessages(ctx context.Context, messageIDs []string, addLabelIDs, removeLabelIDs []string) error
+	FindPeriodSeparatedLabels(ctx context.Context) ([]*gmail.Label, error)
+	FindPeriodSeparatedLabelsWithAnalysis(ctx context.Context) (*LabelAnalysis, error)
+	QuotaUsed() int
+}
+
+var _ LabelService = (*Client)(nil)
+
 type Client struct {
-	service *gmail.Service
-	userID  string
+	service     *gmail.Service
+	userID      string
+	labelFilter *regexp.Regexp
+	excluded    map[string]bool
+	minSegments int
+
+	// includeHidden processes labels hidden from the label list, which are skipped by default
+	includeHidden bool
+
+	// reverse processes nested labels, to be converted back to period-separated names
+	reverse bool
+
+	// allowSlashes processes period-separated labels containing /, whose segments get sanitized
+	allowSlashes bool
+
+	// systemLabels extends the default skipLabels set
+	systemLabels map[string]bool
+
+	// skipPrefixes skips every label whose name starts with one of them, e.g. "[Gmail]."
+	skipPrefixes []string
+
+	// retry wraps calls that must survive transient failures, such as listing labels
+	retry RetryFunc
+
+	quotaMu     sync.Mutex
+	quotaUsed   int
+	quotaBudget int
+
+	// callLog receives a line per API call when set
+	callLog   io.Writer
+	callLogMu sync.Mutex
 }
 
-func NewClient(service *gmail.Service) *Client {
-	return &Client{
+// RetryFunc runs operation, retrying it on transient errors
+type RetryFunc func(ctx context.Context, operation func() error) error
+
+// SetRetry makes the client retry label listing and message batches with fn. Without it calls are attempted once.
+func (c *Client) SetRetry(fn RetryFunc) {
+	c.retry = fn
+}
+
+// Option configures optional Client behavior
+type Option func(*Client)
+
+// WithLabelFilter restricts processable labels to names matching the given expression
+func WithLabelFilter(filter *regexp.Regexp) Option {
+	return func(c *Client) {
+		c.labelFilter = filter
+	}
+}
+
+// WithExcludedLabels skips labels whose names exactly match one of the given names
+func WithExcludedLabels(names []string) Option {
+	return func(c *Client) {
+		if c.excluded == nil {
+			c.excluded = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.excluded[name] = true
+		}
+	}
+}
+
+// WithSkipLabels treats the given names as additional system labels, on top of the defaults.
+// Useful for localized Gmail folders or IMAP imports such as "[Gmail].Sent Mail".
+func WithSkipLabels(names []string) Option {
+	return func(c *Client) {
+		if c.systemLabels == nil {
+			c.systemLabels = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.systemLabels[name] = true
+		}
+	}
+}
+
+// WithSkipPrefixes skips every label whose name starts with one of prefixes
+func WithSkipPrefixes(prefixes []string) Option {
+	return func(c *Client) {
+		c.skipPrefixes = append(c.skipPrefixes, prefixes...)
+	}
+}
+
+// hasSkipPrefix reports whether a label name starts with one of the WithSkipPrefixes prefixes
+func (c *Client) hasSkipPrefix(labelName string) bool {
+	for _, prefix := range c.skipPrefixes {
+		if strings.HasPrefix(labelName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithMinSegments skips labels with fewer than n period-separated segments
+func WithMinSegments(n int) Option {
+	return func(c *Client) {
+		c.minSegments = n
+	}
+}
+
+// WithReverse treats nested labels (A/B) as processable instead of period-separated ones
+func WithReverse() Option {
+	return func(c *Client) {
+		c.reverse = true
+	}
+}
+
+// WithAllowSlashes processes period-separated labels that also contain /, such as A.B/C.D,
+// instead of skipping them as already nested
+func WithAllowSlashes() Option {
+	return func(c *Client) {
+		c.allowSlashes = true
+	}
+}
+
+// WithIncludeHidden processes labels whose LabelListVisibility is labelHide
+func WithIncludeHidden() Option {
+	return func(c *Client) {
+		c.includeHidden = true
+	}
+}
+
+func NewClient(service *gmail.Service, opts ...Option) *Client {
+	client := &Client{
 		service: service,
 		userID:  userID,
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
 
-func (c *Client) GetAllLabels() ([]*gmail.Label, error) {
-	call := c.service.Users.Labels.List(c.userID)
-	response, err := call.Do()
+// GetProfile returns the profile of the authenticated account, including its email address
+func (c *Client) GetProfile(ctx context.Context) (*gmail.Profile, error) {
+	var profile *gmail.Profile
+	err := c.call("users.getProfile", c.userID, QuotaGetProfile, func() error {
+		var err error
+		profile, err = c.service.Users.GetProfile(c.userID).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve labels: %v", err)
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	return profile, nil
+}
+
+// GetAllLabels lists every label in the account. The labels endpoint returns them all in one
+// response without pagination, so the call is retried as a whole on transient failures.
+func (c *Client) GetAllLabels(ctx context.Context) ([]*gmail.Label, error) {
+	var response *gmail.ListLabelsResponse
+	list := func() error {
+		return c.call("labels.list", c.userID, QuotaLabelsList, func() error {
+			var err error
+			response, err = c.service.Users.Labels.List(c.userID).Context(ctx).Do()
+			return err
+		})
+	}
+
+	var err error
+	if c.retry != nil {
+		err = c.retry(ctx, list)
+	} else {
+		err = list()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve labels: %w", err)
 	}
 	return response.Labels, nil
 }
 
-func (c *Client) CreateLabel(name string) (*gmail.Label, error) {
-	label := &gmail.Label{
-		Name:                  name,
-		MessageListVisibility: "show",
-		LabelListVisibility:   "labelShow",
+func (c *Client) GetLabel(ctx context.Context, labelID string) (*gmail.Label, error) {
+	var label *gmail.Label
+	err := c.call("labels.get", labelID, QuotaLabelsGet, func() error {
+		var err error
+		label, err = c.service.Users.Labels.Get(c.userID, labelID).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label %s: %w", labelID, err)
 	}
+	return label, nil
+}
+
+func (c *Client) CreateLabel(ctx context.Context, name string) (*gmail.Label, error) {
+	return c.CreateLabelWithVisibility(ctx, name, "labelShow", "show")
+}
+
+// CreateLabelWithVisibility creates a label with the given label list and message list visibility
+func (c *Client) CreateLabelWithVisibility(ctx context.Context, name, labelListVisibility, messageListVisibility string) (*gmail.Label, error) {
+	return c.createLabel(ctx, &gmail.Label{
+		Name:                  name,
+		MessageListVisibility: messageListVisibility,
+		LabelListVisibility:   labelListVisibility,
+	})
+}
 
-	call := c.service.Users.Labels.Create(c.userID, label)
-	createdLabel, err := call.Do()
+// CreateLabelLike creates a label with the visibility and color of template, e.g. to copy a label
+// under a new name
+func (c *Client) CreateLabelLike(ctx context.Context, name string, template *gmail.Label) (*gmail.Label, error) {
+	return c.createLabel(ctx, &gmail.Label{
+		Name:                  name,
+		MessageListVisibility: template.MessageListVisibility,
+		LabelListVisibility:   template.LabelListVisibility,
+		Color:                 template.Color,
+	})
+}
+
+func (c *Client) createLabel(ctx context.Context, label *gmail.Label) (*gmail.Label, error) {
+	name := label.Name
+	var createdLabel *gmail.Label
+	err := c.call("labels.create", name, QuotaLabelsCreate, func() error {
+		var err error
+		createdLabel, err = c.service.Users.Labels.Create(c.userID, label).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create label %s: %v", name, err)
+		return nil, fmt.Errorf("failed to create label %s: %w", name, err)
 	}
 	return createdLabel, nil
 }
 
-func (c *Client) RenameLabel(labelID, newName string) (*gmail.Label, error) {
-	// Create the label patch with just the name change
-	labelPatch := &gmail.Label{
-		Name: newName,
+// ErrLabelChanged is returned by RenameLabelIfMatches when the label no longer has the name it was
+// analyzed under, e.g. because it was renamed in Gmail or by another run in the meantime
+var ErrLabelChanged = errors.New("label changed since analysis")
+
+func (c *Client) RenameLabel(ctx context.Context, labelID, newName string) (*gmail.Label, error) {
+	// Fetch the current label so its color, visibility and any other fields survive the rename
+	existing, err := c.GetLabel(ctx, labelID)
+	if err != nil {
+		return nil, err
+	}
+	return c.patchName(ctx, existing, newName)
+}
+
+// RenameLabelIfMatches renames a label only if it is still named expectedCurrentName
+func (c *Client) RenameLabelIfMatches(ctx context.Context, labelID, expectedCurrentName, newName string) (*gmail.Label, error) {
+	existing, err := c.GetLabel(ctx, labelID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Name != expectedCurrentName {
+		return nil, fmt.Errorf("label %s is now named '%s', not '%s': %w", labelID, existing.Name, expectedCurrentName, ErrLabelChanged)
 	}
+	return c.patchName(ctx, existing, newName)
+}
+
+// patchName renames the fetched label, sending back every field Gmail returned so none is cleared
+func (c *Client) patchName(ctx context.Context, existing *gmail.Label, newName string) (*gmail.Label, error) {
+	labelID := existing.Id
+	labelPatch := renamePatch(existing, newName)
 
-	call := c.service.Users.Labels.Patch(c.userID, labelID, labelPatch)
-	updatedLabel, err := call.Do()
+	var updatedLabel *gmail.Label
+	err := c.call("labels.patch", labelID, QuotaLabelsPatch, func() error {
+		var err error
+		updatedLabel, err = c.service.Users.Labels.Patch(c.userID, labelID, labelPatch).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to rename label %s to %s: %v", labelID, newName, err)
+		return nil, fmt.Errorf("failed to rename label %s to %s: %w", labelID, newName, err)
 	}
 	return updatedLabel, nil
 }
 
-func (c *Client) DeleteLabel(labelID string) error {
-	call := c.service.Users.Labels.Delete(c.userID, labelID)
-	err := call.Do()
+// renamePatch copies the whole label with only its name changed. Fields Gmail adds to labels
+// later are carried over without this code knowing about them.
+func renamePatch(existing *gmail.Label, newName string) *gmail.Label {
+	patch := *existing
+	patch.Name = newName
+	return &patch
+}
+
+func (c *Client) DeleteLabel(ctx context.Context, labelID string) error {
+	err := c.call("labels.delete", labelID, QuotaLabelsDelete, func() error {
+		return c.service.Users.Labels.Delete(c.userID, labelID).Context(ctx).Do()
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete label %s: %v", labelID, err)
+		return fmt.Errorf("failed to delete label %s: %w", labelID, err)
 	}
 	return nil
 }
 
-func (c *Client) GetMessagesWithLabel(labelID string) ([]string, error) {
-	// Use labelId parameter instead of search query for more reliable results
-	call := c.service.Users.Messages.List(c.userID).LabelIds(labelID)
+func (c *Client) GetMessagesWithLabel(ctx context.Context, labelID string) ([]string, error) {
+	// Use labelId parameter instead of search query for more reliable results. Spam and Trash
+	// are included, since a merge deletes the source label and would strip it from them for good.
+	call := c.service.Users.Messages.List(c.userID).LabelIds(labelID).IncludeSpamTrash(true).MaxResults(500).Context(ctx)
 
 	var messageIDs []string
 
 	for {
-		response, err := call.Do()
+		var response *gmail.ListMessagesResponse
+		err := c.call("messages.list", labelID, QuotaMessagesList, func() error {
+			var err error
+			response, err = call.Do()
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get messages with label %s: %v", labelID, err)
+			return nil, fmt.Errorf("failed to get messages with label %s: %w", labelID, err)
 		}
 
 		for _, message := range response.Messages {
@@ -110,61 +429,212 @@ func (c *Client) GetMessagesWithLabel(labelID string) ([]string, error) {
 	return messageIDs, nil
 }
 
-func (c *Client) ModifyMessageLabels(messageID string, addLabelIDs, removeLabelIDs []string) error {
+// GetNewestMessageDate returns when the label's most recent message was received, or the zero
+// time if the label has no messages. It costs one messages.list and one messages.get call.
+func (c *Client) GetNewestMessageDate(ctx context.Context, labelID string) (time.Time, error) {
+	var response *gmail.ListMessagesResponse
+	err := c.call("messages.list", labelID, QuotaMessagesList, func() error {
+		var err error
+		response, err = c.service.Users.Messages.List(c.userID).LabelIds(labelID).MaxResults(1).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to list newest message with label %s: %w", labelID, err)
+	}
+	if len(response.Messages) == 0 {
+		return time.Time{}, nil
+	}
+
+	messageID := response.Messages[0].Id
+	var message *gmail.Message
+	err = c.call("messages.get", messageID, QuotaMessagesGet, func() error {
+		var err error
+		message, err = c.service.Users.Messages.Get(c.userID, messageID).Format("minimal").Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get message %s: %w", messageID, err)
+	}
+	return time.UnixMilli(message.InternalDate), nil
+}
+
+// GetLabelMessageCount returns the total number of messages carrying a label.
+// It reads MessagesTotal from the label itself instead of paging through every message.
+func (c *Client) GetLabelMessageCount(ctx context.Context, labelID string) (int, error) {
+	var label *gmail.Label
+	err := c.call("labels.get", labelID, QuotaLabelsGet, func() error {
+		var err error
+		label, err = c.service.Users.Labels.Get(c.userID, labelID).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message count for label %s: %w", labelID, err)
+	}
+	return int(label.MessagesTotal), nil
+}
+
+// GetThreadCountWithLabel counts the conversations carrying a label, matching what Gmail's
+// conversation view shows. It pages through threads.list, so it costs more than GetLabelMessageCount.
+func (c *Client) GetThreadCountWithLabel(ctx context.Context, labelID string) (int, error) {
+	call := c.service.Users.Threads.List(c.userID).LabelIds(labelID).MaxResults(500).Context(ctx)
+
+	count := 0
+	for {
+		var response *gmail.ListThreadsResponse
+		err := c.call("threads.list", labelID, QuotaThreadsList, func() error {
+			var err error
+			response, err = call.Do()
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to count threads with label %s: %w", labelID, err)
+		}
+
+		count += len(response.Threads)
+		if response.NextPageToken == "" {
+			return count, nil
+		}
+		call.PageToken(response.NextPageToken)
+	}
+}
+
+func (c *Client) ModifyMessageLabels(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error {
 	modifyRequest := &gmail.ModifyMessageRequest{
 		AddLabelIds:    addLabelIDs,
 		RemoveLabelIds: removeLabelIDs,
 	}
 
-	call := c.service.Users.Messages.Modify(c.userID, messageID, modifyRequest)
-	_, err := call.Do()
+	err := c.call("messages.modify", messageID, QuotaMessagesModify, func() error {
+		_, err := c.service.Users.Messages.Modify(c.userID, messageID, modifyRequest).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to modify message %s labels: %v", messageID, err)
+		return fmt.Errorf("failed to modify message %s labels: %w", messageID, err)
 	}
 	return nil
 }
 
-func (c *Client) LabelExists(labelName string) (*gmail.Label, bool) {
-	labels, err := c.GetAllLabels()
-	if err != nil {
-		return nil, false
-	}
+// BatchModifyMessages adds and removes labels on many messages, in chunks of up to 1000 IDs.
+// Each chunk is retried on its own, so a transient failure does not redo finished chunks.
+func (c *Client) BatchModifyMessages(ctx context.Context, messageIDs []string, addLabelIDs, removeLabelIDs []string) error {
+	for start := 0; start < len(messageIDs); start += maxBatchModifyIDs {
+		end := min(start+maxBatchModifyIDs, len(messageIDs))
+		request := &gmail.BatchModifyMessagesRequest{
+			Ids:            messageIDs[start:end],
+			AddLabelIds:    addLabelIDs,
+			RemoveLabelIds: removeLabelIDs,
+		}
 
-	for _, label := range labels {
-		if label.Name == labelName {
-			return label, true
+		modify := func() error {
+			target := fmt.Sprintf("%d messages", len(request.Ids))
+			return c.call("messages.batchModify", target, QuotaMessagesBatchModify, func() error {
+				return c.service.Users.Messages.BatchModify(c.userID, request).Context(ctx).Do()
+			})
+		}
+
+		var err error
+		if c.retry != nil {
+			err = c.retry(ctx, modify)
+		} else {
+			err = modify()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to modify messages %d-%d of %d: %w", start+1, end, len(messageIDs), err)
 		}
 	}
-	return nil, false
+	return nil
+}
+
+// NormalizeLabelName trims the surrounding whitespace Gmail's UI does not show, so names that
+// look identical compare equal
+func NormalizeLabelName(name string) string {
+	return strings.TrimSpace(name)
+}
+
+// SkippedLabel is a period-separated label excluded from processing, with the reason why
+type SkippedLabel struct {
+	Label  *gmail.Label
+	Reason string
 }
 
 type LabelAnalysis struct {
+	AllLabels         []*gmail.Label
 	ProcessableLabels []*gmail.Label
-	SkippedLabels     []*gmail.Label
+	SkippedLabels     []SkippedLabel
 }
 
-func (c *Client) FindPeriodSeparatedLabels() ([]*gmail.Label, error) {
-	analysis, err := c.FindPeriodSeparatedLabelsWithAnalysis()
+// AllLabelNames returns the names of every label in the analysis, sorted
+func (a *LabelAnalysis) AllLabelNames() []string {
+	names := make([]string, 0, len(a.AllLabels))
+	for _, label := range a.AllLabels {
+		names = append(names, label.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Client) FindPeriodSeparatedLabels(ctx context.Context) ([]*gmail.Label, error) {
+	analysis, err := c.FindPeriodSeparatedLabelsWithAnalysis(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return analysis.ProcessableLabels, nil
 }
 
-func (c *Client) FindPeriodSeparatedLabelsWithAnalysis() (*LabelAnalysis, error) {
-	labels, err := c.GetAllLabels()
+func (c *Client) FindPeriodSeparatedLabelsWithAnalysis(ctx context.Context) (*LabelAnalysis, error) {
+	labels, err := c.GetAllLabels(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return c.ClassifyLabels(labels), nil
+}
 
+// ClassifyLabels splits labels into processable and skipped period-separated labels using the
+// client's filters. It makes no API calls.
+func (c *Client) ClassifyLabels(labels []*gmail.Label) *LabelAnalysis {
 	var processableLabels []*gmail.Label
-	var skippedLabels []*gmail.Label
+	var skippedLabels []SkippedLabel
 
 	for _, label := range labels {
-		if label.Type == "user" && strings.Contains(label.Name, ".") {
+		if label.Type == "user" && strings.Contains(label.Name, c.separator()) {
 			// Skip system labels that should not be processed
-			if shouldSkipLabel(label.Name) {
-				skippedLabels = append(skippedLabels, label)
+			if c.shouldSkipLabel(label.Name) {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonSystem})
+				continue
+			}
+			// Skip whole families of system labels, such as everything under [Gmail].
+			if c.hasSkipPrefix(label.Name) {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonPrefix})
+				continue
+			}
+			// Skip labels that already use the target separator, such as partially migrated A/B.C
+			if strings.Contains(label.Name, c.targetSeparator()) && !c.allowSlashes {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: c.nestedSkipReason()})
+				continue
+			}
+			// Skip labels like A. or A..B, whose empty segments Gmail would reject as a nested name
+			if c.hasEmptySegment(label.Name) {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonEmpty})
+				continue
+			}
+			// Skip labels the user explicitly excluded
+			if c.excluded[label.Name] {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonExcluded})
+				continue
+			}
+			// Skip hidden labels unless the user opted in to them
+			if !c.includeHidden && label.LabelListVisibility == "labelHide" {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonHidden})
+				continue
+			}
+			// Skip labels that are not nested deeply enough for this run
+			if c.minSegments > 0 && len(strings.Split(label.Name, c.separator())) < c.minSegments {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonShallow})
+				continue
+			}
+			// Skip labels outside the user-supplied filter
+			if c.labelFilter != nil && !c.labelFilter.MatchString(label.Name) {
+				skippedLabels = append(skippedLabels, SkippedLabel{Label: label, Reason: SkipReasonFiltered})
 				continue
 			}
 			processableLabels = append(processableLabels, label)
@@ -172,7 +642,8 @@ func (c *Client) FindPeriodSeparatedLabelsWithAnalysis() (*LabelAnalysis, error)
 	}
 
 	return &LabelAnalysis{
+		AllLabels:         labels,
 		ProcessableLabels: processableLabels,
 		SkippedLabels:     skippedLabels,
-	}, nil
+	}
 }
@@ -0,0 +1,184 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// newTestClient returns a Client whose API calls are served by handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := gmail.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("gmail.NewService: %v", err)
+	}
+	return NewClient(service, opts...)
+}
+
+func TestGetMessagesWithLabelIncludesSpamAndTrash(t *testing.T) {
+	var pages int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("includeSpamTrash") != "true" {
+			t.Errorf("includeSpamTrash = %q, want true", query.Get("includeSpamTrash"))
+		}
+		if query.Get("maxResults") != "500" {
+			t.Errorf("maxResults = %q, want 500", query.Get("maxResults"))
+		}
+		pages++
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("pageToken") == "" {
+			w.Write([]byte(`{"messages": [{"id": "m1"}, {"id": "m2"}], "nextPageToken": "next"}`))
+			return
+		}
+		w.Write([]byte(`{"messages": [{"id": "m3"}]}`))
+	})
+
+	ids, err := client.GetMessagesWithLabel(context.Background(), "Label_1")
+	if err != nil {
+		t.Fatalf("GetMessagesWithLabel: %v", err)
+	}
+	if len(ids) != 3 || pages != 2 {
+		t.Errorf("got %v over %d pages, want 3 messages over 2 pages", ids, pages)
+	}
+}
+
+func TestRenameLabelKeepsColor(t *testing.T) {
+	var patched gmail.Label
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"id": "Label_1", "name": "Work.Acme", "type": "user", "color": {"backgroundColor": "#16a766", "textColor": "#ffffff"}}`))
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&patched); err != nil {
+				t.Errorf("decoding patch body: %v", err)
+			}
+			json.NewEncoder(w).Encode(&patched)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	if _, err := client.RenameLabel(context.Background(), "Label_1", "Work/Acme"); err != nil {
+		t.Fatalf("RenameLabel: %v", err)
+	}
+	if patched.Name != "Work/Acme" {
+		t.Errorf("patched name = %q, want Work/Acme", patched.Name)
+	}
+	if patched.Color == nil || patched.Color.BackgroundColor != "#16a766" || patched.Color.TextColor != "#ffffff" {
+		t.Errorf("patched color = %+v, want the label's original color", patched.Color)
+	}
+}
+
+func TestRenamePatchKeepsEveryField(t *testing.T) {
+	existing := &gmail.Label{
+		Id:                    "Label_1",
+		Name:                  "Work.Acme",
+		Type:                  "user",
+		Color:                 &gmail.LabelColor{BackgroundColor: "#16a766", TextColor: "#ffffff"},
+		LabelListVisibility:   "labelShowIfUnread",
+		MessageListVisibility: "hide",
+		MessagesTotal:         12,
+		MessagesUnread:        3,
+		ThreadsTotal:          10,
+		ThreadsUnread:         2,
+		ServerResponse:        googleapi.ServerResponse{HTTPStatusCode: http.StatusOK},
+		ForceSendFields:       []string{"MessagesUnread"},
+		NullFields:            []string{"Color.TextColor"},
+	}
+	// Guard the fixture itself, so a field added to gmail.Label later is covered too
+	fields := reflect.ValueOf(*existing)
+	for i := 0; i < fields.NumField(); i++ {
+		if fields.Field(i).IsZero() {
+			t.Fatalf("fixture leaves gmail.Label.%s unset", fields.Type().Field(i).Name)
+		}
+	}
+
+	patch := renamePatch(existing, "Work/Acme")
+	if patch.Name != "Work/Acme" || existing.Name != "Work.Acme" {
+		t.Errorf("patch name = %q and existing name = %q, want Work/Acme and Work.Acme", patch.Name, existing.Name)
+	}
+	patch.Name = existing.Name
+	if !reflect.DeepEqual(patch, existing) {
+		t.Errorf("patch = %+v, want every field of %+v", patch, existing)
+	}
+}
+
+func TestWithSkipLabelsExtendsDefaults(t *testing.T) {
+	client := NewClient(nil, WithSkipLabels([]string{"[Gmail].Sent Mail", "INBOX.Gesendet"}))
+
+	for _, name := range []string{"INBOX.Trash", "Inbox.Sent", "[Gmail].Sent Mail", "INBOX.Gesendet"} {
+		if !client.shouldSkipLabel(name) {
+			t.Errorf("%s is not skipped", name)
+		}
+	}
+	if client.shouldSkipLabel("Work.Acme") {
+		t.Errorf("Work.Acme is skipped")
+	}
+	if NewClient(nil).shouldSkipLabel("[Gmail].Sent Mail") {
+		t.Errorf("[Gmail].Sent Mail is skipped without WithSkipLabels")
+	}
+}
+
+// skipReasons maps every skipped label name in analysis to the reason it was skipped
+func skipReasons(analysis *LabelAnalysis) map[string]string {
+	reasons := make(map[string]string, len(analysis.SkippedLabels))
+	for _, skipped := range analysis.SkippedLabels {
+		reasons[skipped.Label.Name] = skipped.Reason
+	}
+	return reasons
+}
+
+func TestClassifyLabelsSkipsAlreadyNested(t *testing.T) {
+	labels := []*gmail.Label{
+		{Id: "1", Name: "A/B.C", Type: "user"},
+		{Id: "2", Name: "Work/Projects.2024/Notes", Type: "user"},
+		{Id: "3", Name: "Work.Acme", Type: "user"},
+	}
+
+	analysis := NewClient(nil).ClassifyLabels(labels)
+	reasons := skipReasons(analysis)
+	for _, name := range []string{"A/B.C", "Work/Projects.2024/Notes"} {
+		if reasons[name] != SkipReasonNested {
+			t.Errorf("%s skipped as %q, want %q", name, reasons[name], SkipReasonNested)
+		}
+	}
+	if len(analysis.ProcessableLabels) != 1 || analysis.ProcessableLabels[0].Name != "Work.Acme" {
+		t.Errorf("processable labels = %v, want only Work.Acme", analysis.ProcessableLabels)
+	}
+
+	if analysis := NewClient(nil, WithAllowSlashes()).ClassifyLabels(labels); len(analysis.ProcessableLabels) != 3 {
+		t.Errorf("WithAllowSlashes processes %d labels, want all 3", len(analysis.ProcessableLabels))
+	}
+}
+
+func TestClassifyLabelsSkipsEmptySegments(t *testing.T) {
+	var labels []*gmail.Label
+	for i, name := range []string{"A.", ".A", "A..B", "..."} {
+		labels = append(labels, &gmail.Label{Id: fmt.Sprint(i), Name: name, Type: "user"})
+	}
+
+	analysis := NewClient(nil).ClassifyLabels(labels)
+	if len(analysis.ProcessableLabels) != 0 {
+		t.Errorf("processable labels = %v, want none", analysis.ProcessableLabels)
+	}
+	reasons := skipReasons(analysis)
+	for _, label := range labels {
+		if reasons[label.Name] != SkipReasonEmpty {
+			t.Errorf("%s skipped as %q, want %q", label.Name, reasons[label.Name], SkipReasonEmpty)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package gmail
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Gmail API quota units charged per call, from the published per-method costs
+const (
+	QuotaGetProfile          = 1
+	QuotaLabelsList          = 1
+	QuotaLabelsGet           = 1
+	QuotaLabelsCreate        = 5
+	QuotaLabelsPatch         = 5
+	QuotaLabelsDelete        = 5
+	QuotaMessagesList        = 5
+	QuotaMessagesGet         = 5
+	QuotaThreadsList         = 10
+	QuotaMessagesModify      = 5
+	QuotaMessagesBatchModify = 50
+)
+
+// ErrQuotaBudgetExceeded is returned instead of making a call that would exceed the quota budget
+var ErrQuotaBudgetExceeded = errors.New("quota budget exceeded")
+
+// WithQuotaBudget refuses calls once their estimated quota units would exceed budget
+func WithQuotaBudget(budget int) Option {
+	return func(c *Client) {
+		c.quotaBudget = budget
+	}
+}
+
+// QuotaUsed returns the estimated quota units consumed by this client so far
+func (c *Client) QuotaUsed() int {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	return c.quotaUsed
+}
+
+// spend charges units against the quota budget before a call is made
+func (c *Client) spend(units int) error {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	if c.quotaBudget > 0 && c.quotaUsed+units > c.quotaBudget {
+		return fmt.Errorf("%w: used %d of %d units", ErrQuotaBudgetExceeded, c.quotaUsed, c.quotaBudget)
+	}
+	c.quotaUsed += units
+	return nil
+}
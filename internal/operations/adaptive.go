@@ -0,0 +1,63 @@
+package operations
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	adaptiveMinDelay      = 25 * time.Millisecond // Floor the adaptive delay never drops below
+	adaptiveMaxDelay      = 5 * time.Second       // Ceiling the adaptive delay never exceeds
+	adaptiveIncrease      = 2.0                   // Delay multiplier after a 429
+	adaptiveDecrease      = 0.9                   // Delay multiplier after a streak of successes
+	adaptiveSuccessStreak = 10                    // Successes needed before the delay shrinks
+)
+
+// adaptiveRate tunes the delay between calls from observed throttling: it backs off
+// multiplicatively on 429s and creeps back down after a streak of successes
+type adaptiveRate struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	successes int
+}
+
+func newAdaptiveRate() *adaptiveRate {
+	return &adaptiveRate{delay: adaptiveMinDelay}
+}
+
+// current returns the delay to wait before the next call
+func (r *adaptiveRate) current() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delay
+}
+
+// throttled records a 429 and returns the increased delay
+func (r *adaptiveRate) throttled() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.successes = 0
+	r.delay = min(time.Duration(float64(r.delay)*adaptiveIncrease), adaptiveMaxDelay)
+	return r.delay
+}
+
+// succeeded records a successful call, shrinking the delay after enough in a row
+func (r *adaptiveRate) succeeded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.successes++
+	if r.successes >= adaptiveSuccessStreak {
+		r.successes = 0
+		r.delay = max(time.Duration(float64(r.delay)*adaptiveDecrease), adaptiveMinDelay)
+	}
+}
+
+// isRateLimitError reports whether err is Gmail telling us to slow down
+func isRateLimitError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
@@ -0,0 +1,27 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBackoffBudgetExceeded is returned instead of retrying once the run has spent
+// Config.MaxTotalBackoff waiting out rate limits
+var ErrBackoffBudgetExceeded = errors.New("total backoff budget exceeded, Gmail is throttling persistently")
+
+// spendBackoff adds delay to the time the run has waited between retries, refusing it if that
+// would exceed Config.MaxTotalBackoff
+func (o *Operations) spendBackoff(delay time.Duration) error {
+	if o.config.MaxTotalBackoff <= 0 {
+		return nil
+	}
+
+	o.backoffMu.Lock()
+	defer o.backoffMu.Unlock()
+	if o.backoffTotal+delay > o.config.MaxTotalBackoff {
+		return fmt.Errorf("waited %v of --max-total-backoff %v already: %w", o.backoffTotal.Round(time.Second), o.config.MaxTotalBackoff, ErrBackoffBudgetExceeded)
+	}
+	o.backoffTotal += delay
+	return nil
+}
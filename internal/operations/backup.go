@@ -0,0 +1,156 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+const (
+	DefaultBackupFile = "labels-backup.json" // Default location of the label snapshot
+)
+
+// BackupLabel records a user label's ID and name at the time of the snapshot
+type BackupLabel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Backup is a snapshot of every user label, used by restore to undo any renames since
+type Backup struct {
+	CreatedAt time.Time     `json:"createdAt"`
+	Labels    []BackupLabel `json:"labels"`
+}
+
+// Backup writes a snapshot of every user label's ID and name to path
+func (o *Operations) Backup(ctx context.Context, path string) error {
+	o.println("💾 Backing up Gmail labels...")
+
+	labels, err := o.client.GetAllLabels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+
+	backup := Backup{CreatedAt: time.Now()}
+	for _, label := range labels {
+		if label.Type != "user" {
+			continue // System labels cannot be renamed
+		}
+		backup.Labels = append(backup.Labels, BackupLabel{ID: label.Id, Name: label.Name})
+	}
+	sort.Slice(backup.Labels, func(i, j int) bool {
+		return backup.Labels[i].Name < backup.Labels[j].Name
+	})
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode backup: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write backup %s: %v", path, err)
+	}
+
+	o.printf("✅ Backed up %d labels to %s\n", len(backup.Labels), path)
+	return nil
+}
+
+// loadBackup reads the snapshot written by Backup
+func loadBackup(path string) (*Backup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read backup %s: %v", path, err)
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("unable to parse backup %s: %v", path, err)
+	}
+	return &backup, nil
+}
+
+// Restore renames every label whose name changed since the snapshot at path back to its
+// snapshot name. Labels are matched by ID, so intervening renames don't matter. Labels
+// deleted since the snapshot are reported, their messages cannot be restored.
+func (o *Operations) Restore(ctx context.Context, path string) error {
+	backup, err := loadBackup(path)
+	if err != nil {
+		return err
+	}
+	o.printf("♻️  Restoring labels from backup taken %s\n", backup.CreatedAt.Format(time.RFC3339))
+
+	labels, err := o.client.GetAllLabels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+	labelsByID := make(map[string]*gmailAPI.Label)
+	for _, label := range labels {
+		labelsByID[label.Id] = label
+	}
+
+	var changed []BackupLabel
+	for _, snapshot := range backup.Labels {
+		current, exists := labelsByID[snapshot.ID]
+		if !exists {
+			o.printf("   ⚠️  %s (ID: %s) was deleted since the backup, its messages cannot be restored\n", snapshot.Name, snapshot.ID)
+			continue
+		}
+		if current.Name != snapshot.Name {
+			changed = append(changed, snapshot)
+		}
+	}
+
+	if len(changed) == 0 {
+		o.println("✅ Nothing to restore, every label still has its backed up name")
+		return nil
+	}
+
+	o.printf("\n📋 %d labels will be renamed:\n", len(changed))
+	for _, snapshot := range changed {
+		o.printf("   %s → %s\n", labelsByID[snapshot.ID].Name, snapshot.Name)
+	}
+	o.println()
+
+	if !o.confirm(fmt.Sprintf("Restore %d labels?", len(changed))) {
+		o.println("🛑 Aborted. No labels were changed.")
+		return nil
+	}
+
+	restored, failed := 0, 0
+	for i, snapshot := range changed {
+		if ctx.Err() != nil {
+			break
+		}
+		current := labelsByID[snapshot.ID]
+		o.printf("\n[%d/%d] Restoring: %s → %s\n", i+1, len(changed), current.Name, snapshot.Name)
+
+		err := o.retryWithBackoff(ctx, func() error {
+			_, err := o.client.RenameLabel(ctx, snapshot.ID, snapshot.Name)
+			return err
+		})
+		if err != nil {
+			o.printf("❌ Failed: %v\n", err)
+			failed++
+			continue
+		}
+
+		o.withRateLimit(ctx)
+
+		restored++
+		o.logger().Info("label restored", "label_id", snapshot.ID, "from", current.Name, "to", snapshot.Name)
+		o.printf("✅ Restored: %s → %s\n", current.Name, snapshot.Name)
+	}
+
+	o.printf("\n🎉 Restore completed! Restored %d/%d labels successfully.\n", restored, len(changed))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d restores failed: %w", failed, len(changed), ErrLabelsFailed)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"sort"
+	"strings"
+)
+
+// Clean deletes processable period-separated labels that have no messages. A label is kept while
+// any label nested under it (e.g. Work.Acme.Invoices under Work.Acme) is not being deleted too.
+func (o *Operations) Clean(ctx context.Context, dryRun bool) error {
+	o.println("🧹 Looking for empty period-separated labels...")
+
+	analysis, err := o.client.FindPeriodSeparatedLabelsWithAnalysis(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find labels: %v", err)
+	}
+
+	var empty []*analyzer.LabelTransformation
+	for _, label := range analysis.ProcessableLabels {
+		count, err := o.client.GetLabelMessageCount(ctx, label.Id)
+		if err != nil {
+			o.printf("   ⚠️  Warning: Could not count messages for label %s, keeping it: %v\n", label.Name, err)
+			continue
+		}
+		if count == 0 {
+			empty = append(empty, &analyzer.LabelTransformation{OriginalLabel: label.Name, OriginalID: label.Id})
+		}
+	}
+
+	// Decide deepest labels first, so a parent knows whether all of its children are going away
+	separators := o.config.ParseOptions.SourceSeparators()
+	sort.Slice(empty, func(i, j int) bool {
+		if depthI, depthJ := separatorCount(empty[i].OriginalLabel, separators), separatorCount(empty[j].OriginalLabel, separators); depthI != depthJ {
+			return depthI > depthJ
+		}
+		return empty[i].OriginalLabel < empty[j].OriginalLabel
+	})
+
+	allNames := analysis.AllLabelNames()
+	deleting := make(map[string]bool)
+	var toDelete []*analyzer.LabelTransformation
+	for _, transformation := range empty {
+		if child := keptChild(transformation.OriginalLabel, allNames, separators, deleting); child != "" {
+			o.printf("   ⏭️  Keeping %s: child label %s is not being deleted\n", transformation.OriginalLabel, child)
+			continue
+		}
+		deleting[transformation.OriginalLabel] = true
+		toDelete = append(toDelete, transformation)
+	}
+
+	if len(toDelete) == 0 {
+		o.println("✅ No empty period-separated labels to delete")
+		return nil
+	}
+
+	o.printf("\n🗑️  %d empty labels will be deleted:\n", len(toDelete))
+	for _, transformation := range toDelete {
+		o.printf("   - %s\n", transformation.OriginalLabel)
+	}
+	o.println()
+
+	if dryRun {
+		o.println("🔍 Dry run: no labels were deleted")
+		return nil
+	}
+
+	if !o.confirm(fmt.Sprintf("Delete %d empty labels?", len(toDelete))) {
+		o.println("🛑 Aborted. No labels were deleted.")
+		return nil
+	}
+
+	deleted, failed := 0, 0
+	for i, transformation := range toDelete {
+		if ctx.Err() != nil {
+			break
+		}
+		o.printf("\n[%d/%d] Deleting: %s\n", i+1, len(toDelete), transformation.OriginalLabel)
+		if err := o.deleteEmptyLabel(ctx, transformation); err != nil {
+			o.printf("❌ Failed: %s: %v\n", transformation.OriginalLabel, err)
+			failed++
+			continue
+		}
+		deleted++
+	}
+
+	o.printf("\n🎉 Clean completed! Deleted %d/%d empty labels.\n", deleted, len(toDelete))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deletions failed: %w", failed, len(toDelete), ErrLabelsFailed)
+	}
+	return nil
+}
+
+// keptChild returns the name of a label nested under labelName at any of separators that is not
+// being deleted, if any
+func keptChild(labelName string, allNames, separators []string, deleting map[string]bool) string {
+	for _, name := range allNames {
+		if deleting[name] {
+			continue
+		}
+		for _, separator := range separators {
+			if strings.HasPrefix(name, labelName+separator) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// separatorCount counts the separators in a label name, i.e. its depth below the root
+func separatorCount(labelName string, separators []string) int {
+	count := 0
+	for _, separator := range separators {
+		count += strings.Count(labelName, separator)
+	}
+	return count
+}
@@ -0,0 +1,46 @@
+package operations
+
+import (
+	"context"
+	"gmail-label-fixer/internal/analyzer"
+	"gmail-label-fixer/internal/gmail"
+	"strings"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestCleanKeepsParentsOfNonEmptyChildren(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     string
+		child      string
+		options    analyzer.ParseOptions
+		clientOpts []gmail.Option
+	}{
+		{name: "periods", parent: "Work.Acme", child: "Work.Acme.Invoices"},
+		{name: "reverse", parent: "Work/Acme", child: "Work/Acme/Invoices", options: analyzer.ParseOptions{Reverse: true}, clientOpts: []gmail.Option{gmail.WithReverse()}},
+		{name: "mixed separators", parent: "Work.Acme", child: "Work.Acme/Invoices", options: analyzer.ParseOptions{MixedSeparators: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeService([]*gmailAPI.Label{
+				{Id: "parent", Name: tt.parent, Type: "user"},
+				{Id: "child", Name: tt.child, Type: "user"},
+			}, tt.clientOpts...)
+			fake.AddMessage("m1", "child")
+			var output strings.Builder
+
+			ops := newTestOperations(fake, func(config *Config) {
+				config.ParseOptions = tt.options
+				config.Output = &output
+			})
+			if err := ops.Clean(context.Background(), true); err != nil {
+				t.Fatalf("Clean: %v", err)
+			}
+			if want := "Keeping " + tt.parent + ": child label " + tt.child; !strings.Contains(output.String(), want) {
+				t.Errorf("output does not contain %q:\n%s", want, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package operations
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// lineColors maps the status emoji a line starts with to the color of that line
+var lineColors = []struct {
+	prefix string
+	color  string
+}{
+	{"✅", colorGreen},
+	{"🎉", colorGreen},
+	{"⚠️", colorYellow},
+	{"⏭️", colorYellow},
+	{"⏳", colorYellow},
+	{"🛑", colorYellow},
+	{"⏰", colorYellow},
+	{"❌", colorRed},
+}
+
+// useColor reports whether status output is colored: only on terminals, and never when
+// disabled with --no-color or the NO_COLOR environment variable
+func (o *Operations) useColor() bool {
+	if o.config.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(o.output())
+}
+
+// colorize colors each line of text according to its leading status emoji
+func colorize(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		for _, lc := range lineColors {
+			if strings.HasPrefix(trimmed, lc.prefix) {
+				body := strings.TrimSuffix(line, "\n")
+				lines[i] = lc.color + body + colorReset + line[len(body):]
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "")
+}
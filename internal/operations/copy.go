@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// copyToNewLabel creates the nested label and copies the source label's messages into it, for
+// --preserve-original. The new label gets the source's visibility and color, and the source label
+// is left as it was.
+func (o *Operations) copyToNewLabel(ctx context.Context, transformation *analyzer.LabelTransformation) error {
+	o.detailf("   Copying label: %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
+
+	source, exists, err := o.findLabel(ctx, transformation.OriginalLabel)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("label '%s' not found", transformation.OriginalLabel)
+	}
+
+	var created *gmailAPI.Label
+	err = o.retryWithBackoff(ctx, func() error {
+		var err error
+		created, err = o.client.CreateLabelLike(ctx, transformation.NestedStructure, source)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create label: %w", err)
+	}
+	o.labelCreated(created)
+
+	o.withRateLimit(ctx)
+
+	return o.copyMessages(ctx, transformation, created, true)
+}
+
+// copyMessages adds target to every message carrying the source label, without removing the
+// source label from them. created tells whether target was made for this copy, so undo deletes it.
+func (o *Operations) copyMessages(ctx context.Context, transformation *analyzer.LabelTransformation, target *gmailAPI.Label, created bool) error {
+	var messageIDs []string
+	err := o.retryWithBackoff(ctx, func() error {
+		var err error
+		messageIDs, err = o.client.GetMessagesWithLabel(ctx, transformation.OriginalID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list messages to copy: %w", err)
+	}
+
+	if len(messageIDs) > 0 {
+		// The client retries each chunk itself
+		if err := o.client.BatchModifyMessages(ctx, messageIDs, []string{target.Id}, nil); err != nil {
+			return fmt.Errorf("failed to copy messages: %w", err)
+		}
+		o.withRateLimit(ctx)
+	}
+	o.analyzer.ForgetCount(target.Id)
+	o.copiedMessages.Add(int64(len(messageIDs)))
+	o.recordCopy(transformation.OriginalID, transformation.OriginalLabel, target, messageIDs, created)
+
+	o.logger().Info("label copied", "label_id", transformation.OriginalID, "from", transformation.OriginalLabel, "target_id", target.Id, "to", target.Name, "messages_copied", len(messageIDs))
+
+	o.detailf("   ✅ Copied into: %s (ID: %s)\n", target.Name, target.Id)
+	o.detailf("   📋 Copied %d messages, %s is kept as it was\n", len(messageIDs), transformation.OriginalLabel)
+	return nil
+}
+
+// printCopied reports how many messages --preserve-original copied during the run
+func (o *Operations) printCopied() {
+	if o.config.PreserveOriginal {
+		o.printf("📋 Copied %d messages into new labels, the original labels were kept\n", o.copiedMessages.Load())
+	}
+}
@@ -0,0 +1,79 @@
+package operations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestPreserveOriginalCopiesLabelAndUndoRemovesCopy(t *testing.T) {
+	color := &gmailAPI.LabelColor{BackgroundColor: "#16a766", TextColor: "#ffffff"}
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "acme", Name: "Work.Acme", Type: "user", LabelListVisibility: "labelShowIfUnread", MessageListVisibility: "hide", Color: color},
+	})
+	fake.AddMessage("m1", "acme")
+	fake.AddMessage("m2", "acme")
+
+	ops := newTestOperations(fake, func(config *Config) {
+		config.PreserveOriginal = true
+		config.JournalPath = filepath.Join(t.TempDir(), DefaultJournalFile)
+	})
+	if _, err := ops.FixAllLabels(context.Background()); err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+
+	if counts := labelCounts(t, fake); counts["Work.Acme"] != 2 || counts["Work/Acme"] != 2 {
+		t.Errorf("labels after copy = %v, want Work.Acme and Work/Acme with 2 messages each", counts)
+	}
+	copied := fake.byName("Work/Acme")
+	if copied == nil {
+		t.Fatal("Work/Acme was not created")
+	}
+	if copied.LabelListVisibility != "labelShowIfUnread" || copied.MessageListVisibility != "hide" {
+		t.Errorf("copy visibility = %s/%s, want labelShowIfUnread/hide", copied.LabelListVisibility, copied.MessageListVisibility)
+	}
+	if copied.Color == nil || copied.Color.BackgroundColor != color.BackgroundColor || copied.Color.TextColor != color.TextColor {
+		t.Errorf("copy color = %+v, want %+v", copied.Color, color)
+	}
+
+	if err := ops.Undo(context.Background()); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	counts := labelCounts(t, fake)
+	if _, exists := counts["Work/Acme"]; exists {
+		t.Errorf("undo kept the copied label Work/Acme")
+	}
+	if counts["Work.Acme"] != 2 {
+		t.Errorf("Work.Acme has %d messages after undo, want 2", counts["Work.Acme"])
+	}
+}
+
+func TestUndoCopyIntoExistingLabel(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "old", Name: "Work.Acme", Type: "user"},
+		{Id: "new", Name: "Work/Acme", Type: "user"},
+	})
+	fake.AddMessage("m1", "old")
+	fake.AddMessage("m2", "new")
+
+	ops := newTestOperations(fake, func(config *Config) {
+		config.PreserveOriginal = true
+		config.OnConflict = OnConflictMerge
+		config.JournalPath = filepath.Join(t.TempDir(), DefaultJournalFile)
+	})
+	if _, err := ops.FixAllLabels(context.Background()); err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+	if counts := labelCounts(t, fake); counts["Work/Acme"] != 2 {
+		t.Fatalf("Work/Acme has %d messages after the copy, want 2", counts["Work/Acme"])
+	}
+
+	if err := ops.Undo(context.Background()); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if counts := labelCounts(t, fake); counts["Work/Acme"] != 1 || counts["Work.Acme"] != 1 {
+		t.Errorf("labels after undo = %v, want both back to 1 message", counts)
+	}
+}
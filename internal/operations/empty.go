@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+)
+
+// shouldDelete reports whether a transformation's label is deleted instead of renamed. Labels
+// are never deleted under --preserve-original.
+func (o *Operations) shouldDelete(transformation *analyzer.LabelTransformation) bool {
+	return o.config.DeleteEmpty && !o.config.PreserveOriginal && transformation.MessageCount == 0
+}
+
+// dropSmallLabels removes transformations with fewer than Config.MinMessages messages.
+// Empty labels are kept when they are going to be deleted instead.
+func (o *Operations) dropSmallLabels(transformations []*analyzer.LabelTransformation) (kept, skipped []*analyzer.LabelTransformation) {
+	for _, transformation := range transformations {
+		if transformation.MessageCount < o.config.MinMessages && !o.shouldDelete(transformation) {
+			skipped = append(skipped, transformation)
+			continue
+		}
+		kept = append(kept, transformation)
+	}
+
+	if len(skipped) > 0 {
+		o.printf("⏭️  Skipping %d labels with fewer than %d messages:\n", len(skipped), o.config.MinMessages)
+		for _, transformation := range skipped {
+			o.printf("   - %s (%d messages)\n", transformation.OriginalLabel, transformation.MessageCount)
+		}
+	}
+	return kept, skipped
+}
+
+// deleteEmptyLabel deletes a period-separated label after confirming it still has no messages,
+// since a failed count during analysis is also reported as zero
+func (o *Operations) deleteEmptyLabel(ctx context.Context, transformation *analyzer.LabelTransformation) error {
+	ctx, cancel := withoutCancel(ctx)
+	defer cancel()
+
+	var count int
+	err := o.retryWithBackoff(ctx, func() error {
+		var err error
+		count, err = o.client.GetLabelMessageCount(ctx, transformation.OriginalID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not confirm label is empty: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("label is no longer empty (%d messages), not deleting", count)
+	}
+
+	o.detailf("   Deleting empty label: %s\n", transformation.OriginalLabel)
+	err = o.retryWithBackoff(ctx, func() error {
+		return o.client.DeleteLabel(ctx, transformation.OriginalID)
+	})
+	if err != nil {
+		o.logger().Error("label delete failed", "label_id", transformation.OriginalID, "name", transformation.OriginalLabel, "error", err)
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	o.labelDeleted(transformation.OriginalLabel)
+	o.withRateLimit(ctx)
+
+	o.logger().Info("empty label deleted", "label_id", transformation.OriginalID, "name", transformation.OriginalLabel)
+	o.detailf("   🗑️  Deleted empty label: %s\n", transformation.OriginalLabel)
+	return nil
+}
+
+// describePlan formats the planned change for one transformation
+func (o *Operations) describePlan(transformation *analyzer.LabelTransformation) string {
+	if o.shouldDelete(transformation) {
+		return transformation.OriginalLabel + " → (delete, no messages)"
+	}
+	return transformation.OriginalLabel + " → " + transformation.NestedStructure
+}
@@ -0,0 +1,10 @@
+package operations
+
+import "errors"
+
+// Outcomes callers can tell apart with errors.Is, e.g. to choose a process exit code
+var (
+	ErrLabelsFailed     = errors.New("some labels failed")
+	ErrNothingToProcess = errors.New("no period-separated labels to process")
+	ErrAborted          = errors.New("aborted at the first failure")
+)
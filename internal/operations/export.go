@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// exportHeader lists the columns written by Export
+var exportHeader = []string{"id", "name", "type", "is_period_separated", "message_count", "proposed_nested_name"}
+
+// Export writes every label with its classification and message count to a CSV file
+func (o *Operations) Export(ctx context.Context, path string) error {
+	o.println("📤 Exporting Gmail labels...")
+
+	analysis, err := o.client.FindPeriodSeparatedLabelsWithAnalysis(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+
+	processable := make(map[string]bool)
+	for _, label := range analysis.ProcessableLabels {
+		processable[label.Id] = true
+	}
+
+	labels := make([]*gmailAPI.Label, len(analysis.AllLabels))
+	copy(labels, analysis.AllLabels)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create export file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(exportHeader); err != nil {
+		return fmt.Errorf("failed to write export header: %v", err)
+	}
+
+	// Redraw a single progress line on terminals only, \r would litter logs and pipes
+	progress := isTerminal(o.output())
+	for i, label := range labels {
+		if progress {
+			o.printf("\r   [%d/%d] Counting messages...", i+1, len(labels))
+		}
+
+		messageCount := ""
+		count, err := o.analyzer.MessageCount(ctx, label.Id)
+		if err != nil {
+			if progress {
+				o.println()
+			}
+			o.printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", label.Name, err)
+		} else {
+			messageCount = strconv.Itoa(count)
+		}
+
+		// The proposed name is only set for labels a fix would actually transform
+		isPeriodSeparated := strings.Contains(label.Name, ".")
+		proposedName := ""
+		if processable[label.Id] {
+			if transformation := o.analyzer.Parse(label.Name); transformation != nil {
+				proposedName = transformation.NestedStructure
+			}
+		}
+
+		row := []string{
+			label.Id,
+			label.Name,
+			label.Type,
+			strconv.FormatBool(isPeriodSeparated),
+			messageCount,
+			proposedName,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write export row for %s: %v", label.Name, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export file %s: %v", path, err)
+	}
+
+	if progress {
+		o.println()
+	}
+	o.printf("✅ Exported %d labels to %s\n", len(labels), path)
+	return nil
+}
@@ -0,0 +1,62 @@
+package operations
+
+import (
+	"context"
+	"gmail-label-fixer/internal/analyzer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestExportHonorsCountMode(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{{Id: "work", Name: "Work.Acme", Type: "user"}})
+	fake.AddMessage("m1", "work")
+	path := filepath.Join(t.TempDir(), "labels.csv")
+
+	ops := newTestOperations(fake, func(config *Config) { config.CountMode = analyzer.CountModeThreads })
+	if err := ops.Export(context.Background(), path); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if fake.Calls["GetThreadCountWithLabel"] != 1 || fake.Calls["GetLabelMessageCount"] != 0 {
+		t.Errorf("counted %d threads and %d messages, want threads only", fake.Calls["GetThreadCountWithLabel"], fake.Calls["GetLabelMessageCount"])
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	if !strings.Contains(string(data), "work,Work.Acme,user,true,1,Work/Acme") {
+		t.Errorf("export is missing the Work.Acme row:\n%s", data)
+	}
+}
+
+func TestExportMarksSkippedPeriodLabels(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "sent", Name: "INBOX.Sent", Type: "user"},
+		{Id: "acme", Name: "Work.Acme", Type: "user"},
+		{Id: "home", Name: "Home", Type: "user"},
+	})
+	path := filepath.Join(t.TempDir(), "labels.csv")
+	var output strings.Builder
+
+	ops := newTestOperations(fake, func(config *Config) { config.Output = &output })
+	if err := ops.Export(context.Background(), path); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	for _, row := range []string{"sent,INBOX.Sent,user,true,0,\n", "acme,Work.Acme,user,true,0,Work/Acme\n", "home,Home,user,false,0,\n"} {
+		if !strings.Contains(string(data), row) {
+			t.Errorf("export is missing row %q:\n%s", row, data)
+		}
+	}
+	if strings.Contains(output.String(), "\r") {
+		t.Errorf("progress line redrawn with \\r on a non-terminal:\n%q", output.String())
+	}
+}
@@ -0,0 +1,387 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/gmail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+var _ gmail.LabelService = (*fakeService)(nil)
+
+// fakeService is an in-memory mailbox of labels and the messages carrying them
+type fakeService struct {
+	mu         sync.Mutex
+	labels     map[string]*gmailAPI.Label // keyed by ID
+	messages   map[string]map[string]bool // message ID → label IDs
+	dates      map[string]time.Time       // message ID → received date, for messages given one
+	classifier *gmail.Client
+	nextID     int
+
+	// Errors queues errors returned by the next calls of the named method, e.g. "RenameLabel",
+	// one per call, to simulate rate limits and transient failures
+	Errors map[string][]error
+
+	// Calls counts invocations per method name
+	Calls map[string]int
+}
+
+// newFakeService returns a fake holding labels. The options filter labels just like they do on a real Client.
+func newFakeService(labels []*gmailAPI.Label, opts ...gmail.Option) *fakeService {
+	s := &fakeService{
+		labels:     make(map[string]*gmailAPI.Label),
+		messages:   make(map[string]map[string]bool),
+		dates:      make(map[string]time.Time),
+		classifier: gmail.NewClient(nil, opts...),
+		Errors:     make(map[string][]error),
+		Calls:      make(map[string]int),
+	}
+	for _, label := range labels {
+		if label.Type == "" {
+			label.Type = "user"
+		}
+		if label.Id == "" {
+			label.Id = s.newID()
+		}
+		s.labels[label.Id] = label
+	}
+	return s
+}
+
+// AddMessage puts a message carrying the given label IDs into the mailbox
+func (s *fakeService) AddMessage(messageID string, labelIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.messages[messageID] == nil {
+		s.messages[messageID] = make(map[string]bool)
+	}
+	for _, labelID := range labelIDs {
+		s.messages[messageID][labelID] = true
+	}
+}
+
+// SetMessageDate sets when a message was received, as reported by GetNewestMessageDate
+func (s *fakeService) SetMessageDate(messageID string, date time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dates[messageID] = date
+}
+
+// newID hands out sequential label IDs; the caller must hold mu or be constructing the fake
+func (s *fakeService) newID() string {
+	s.nextID++
+	return fmt.Sprintf("Label_%d", s.nextID)
+}
+
+// call records an invocation and pops the next queued error for method, if any
+func (s *fakeService) call(method string) error {
+	s.Calls[method]++
+	if queued := s.Errors[method]; len(queued) > 0 {
+		s.Errors[method] = queued[1:]
+		return queued[0]
+	}
+	return nil
+}
+
+func (s *fakeService) byName(name string) *gmailAPI.Label {
+	for _, label := range s.labels {
+		if label.Name == name {
+			return label
+		}
+	}
+	return nil
+}
+
+func (s *fakeService) messageCount(labelID string) int {
+	count := 0
+	for _, labels := range s.messages {
+		if labels[labelID] {
+			count++
+		}
+	}
+	return count
+}
+
+// GetAllLabels returns copies of every label sorted by name
+func (s *fakeService) GetAllLabels(ctx context.Context) ([]*gmailAPI.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("GetAllLabels"); err != nil {
+		return nil, err
+	}
+
+	labels := make([]*gmailAPI.Label, 0, len(s.labels))
+	for _, label := range s.labels {
+		copied := *label
+		labels = append(labels, &copied)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+func (s *fakeService) GetLabelMessageCount(ctx context.Context, labelID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("GetLabelMessageCount"); err != nil {
+		return 0, err
+	}
+	if s.labels[labelID] == nil {
+		return 0, fmt.Errorf("label %s not found", labelID)
+	}
+	return s.messageCount(labelID), nil
+}
+
+// GetThreadCountWithLabel counts every message as its own conversation, since the fake has no threads
+func (s *fakeService) GetThreadCountWithLabel(ctx context.Context, labelID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("GetThreadCountWithLabel"); err != nil {
+		return 0, err
+	}
+	if s.labels[labelID] == nil {
+		return 0, fmt.Errorf("label %s not found", labelID)
+	}
+	return s.messageCount(labelID), nil
+}
+
+func (s *fakeService) CreateLabelWithVisibility(ctx context.Context, name, labelListVisibility, messageListVisibility string) (*gmailAPI.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("CreateLabelWithVisibility"); err != nil {
+		return nil, err
+	}
+	if s.byName(name) != nil {
+		return nil, fmt.Errorf("label %s already exists", name)
+	}
+
+	label := &gmailAPI.Label{
+		Id:                    s.newID(),
+		Name:                  name,
+		Type:                  "user",
+		LabelListVisibility:   labelListVisibility,
+		MessageListVisibility: messageListVisibility,
+	}
+	s.labels[label.Id] = label
+	copied := *label
+	return &copied, nil
+}
+
+// CreateLabelLike creates a label with template's visibility and color
+func (s *fakeService) CreateLabelLike(ctx context.Context, name string, template *gmailAPI.Label) (*gmailAPI.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("CreateLabelLike"); err != nil {
+		return nil, err
+	}
+	if s.byName(name) != nil {
+		return nil, fmt.Errorf("label %s already exists", name)
+	}
+
+	label := &gmailAPI.Label{
+		Id:                    s.newID(),
+		Name:                  name,
+		Type:                  "user",
+		LabelListVisibility:   template.LabelListVisibility,
+		MessageListVisibility: template.MessageListVisibility,
+		Color:                 template.Color,
+	}
+	s.labels[label.Id] = label
+	copied := *label
+	return &copied, nil
+}
+
+func (s *fakeService) RenameLabel(ctx context.Context, labelID, newName string) (*gmailAPI.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("RenameLabel"); err != nil {
+		return nil, err
+	}
+	label := s.labels[labelID]
+	if label == nil {
+		return nil, fmt.Errorf("label %s not found", labelID)
+	}
+	if existing := s.byName(newName); existing != nil && existing.Id != labelID {
+		return nil, fmt.Errorf("label %s already exists", newName)
+	}
+
+	label.Name = newName
+	s.createParents(newName)
+	copied := *label
+	return &copied, nil
+}
+
+// createParents adds the missing ancestors of a nested name, as Gmail does when a label is
+// renamed under a parent that doesn't exist yet; the caller must hold mu
+func (s *fakeService) createParents(name string) {
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if s.byName(parent) == nil {
+			id := s.newID()
+			s.labels[id] = &gmailAPI.Label{Id: id, Name: parent, Type: "user"}
+		}
+	}
+}
+
+// RenameLabelIfMatches renames a label like RenameLabel, unless it is no longer named expectedCurrentName
+func (s *fakeService) RenameLabelIfMatches(ctx context.Context, labelID, expectedCurrentName, newName string) (*gmailAPI.Label, error) {
+	s.mu.Lock()
+	if label := s.labels[labelID]; label != nil && label.Name != expectedCurrentName {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("label %s is now named '%s', not '%s': %w", labelID, label.Name, expectedCurrentName, gmail.ErrLabelChanged)
+	}
+	s.mu.Unlock()
+	return s.RenameLabel(ctx, labelID, newName)
+}
+
+func (s *fakeService) DeleteLabel(ctx context.Context, labelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("DeleteLabel"); err != nil {
+		return err
+	}
+	if s.labels[labelID] == nil {
+		return fmt.Errorf("label %s not found", labelID)
+	}
+
+	delete(s.labels, labelID)
+	for _, labels := range s.messages {
+		delete(labels, labelID)
+	}
+	return nil
+}
+
+func (s *fakeService) GetMessagesWithLabel(ctx context.Context, labelID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("GetMessagesWithLabel"); err != nil {
+		return nil, err
+	}
+
+	var messageIDs []string
+	for messageID, labels := range s.messages {
+		if labels[labelID] {
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
+	sort.Strings(messageIDs)
+	return messageIDs, nil
+}
+
+// GetNewestMessageDate returns the latest SetMessageDate date among the label's messages
+func (s *fakeService) GetNewestMessageDate(ctx context.Context, labelID string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("GetNewestMessageDate"); err != nil {
+		return time.Time{}, err
+	}
+
+	var newest time.Time
+	for messageID, labels := range s.messages {
+		if labels[labelID] && s.dates[messageID].After(newest) {
+			newest = s.dates[messageID]
+		}
+	}
+	return newest, nil
+}
+
+func (s *fakeService) ModifyMessageLabels(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("ModifyMessageLabels"); err != nil {
+		return err
+	}
+	labels := s.messages[messageID]
+	if labels == nil {
+		return fmt.Errorf("message %s not found", messageID)
+	}
+
+	for _, labelID := range addLabelIDs {
+		labels[labelID] = true
+	}
+	for _, labelID := range removeLabelIDs {
+		delete(labels, labelID)
+	}
+	return nil
+}
+
+func (s *fakeService) BatchModifyMessages(ctx context.Context, messageIDs []string, addLabelIDs, removeLabelIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.call("BatchModifyMessages"); err != nil {
+		return err
+	}
+	for _, messageID := range messageIDs {
+		labels := s.messages[messageID]
+		if labels == nil {
+			continue // Gmail ignores unknown IDs in a batch
+		}
+		for _, labelID := range addLabelIDs {
+			labels[labelID] = true
+		}
+		for _, labelID := range removeLabelIDs {
+			delete(labels, labelID)
+		}
+	}
+	return nil
+}
+
+// fakeQuotaCosts estimates the units a real Client would spend per method
+var fakeQuotaCosts = map[string]int{
+	"GetAllLabels":              gmail.QuotaLabelsList,
+	"GetLabelMessageCount":      gmail.QuotaLabelsGet,
+	"GetThreadCountWithLabel":   gmail.QuotaThreadsList,
+	"CreateLabelWithVisibility": gmail.QuotaLabelsCreate,
+	"CreateLabelLike":           gmail.QuotaLabelsCreate,
+	"RenameLabel":               gmail.QuotaLabelsGet + gmail.QuotaLabelsPatch,
+	"DeleteLabel":               gmail.QuotaLabelsDelete,
+	"GetMessagesWithLabel":      gmail.QuotaMessagesList,
+	"GetNewestMessageDate":      gmail.QuotaMessagesList + gmail.QuotaMessagesGet,
+	"ModifyMessageLabels":       gmail.QuotaMessagesModify,
+	"BatchModifyMessages":       gmail.QuotaMessagesBatchModify,
+}
+
+// QuotaUsed estimates the quota units the recorded calls would have cost
+func (s *fakeService) QuotaUsed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used := 0
+	for method, calls := range s.Calls {
+		used += fakeQuotaCosts[method] * calls
+	}
+	return used
+}
+
+func (s *fakeService) FindPeriodSeparatedLabels(ctx context.Context) ([]*gmailAPI.Label, error) {
+	analysis, err := s.FindPeriodSeparatedLabelsWithAnalysis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.ProcessableLabels, nil
+}
+
+func (s *fakeService) FindPeriodSeparatedLabelsWithAnalysis(ctx context.Context) (*gmail.LabelAnalysis, error) {
+	labels, err := s.GetAllLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.classifier.ClassifyLabels(labels), nil
+}
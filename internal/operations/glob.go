@@ -0,0 +1,48 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"path"
+	"strings"
+)
+
+// isGlob reports whether a --label value is a pattern rather than an exact label name
+func isGlob(labelName string) bool {
+	return strings.ContainsAny(labelName, "*?[")
+}
+
+// findLabelsByGlob finds the labels matching a glob pattern, with all their children. Each *
+// matches within a single segment, so Work.*.Invoices matches Work.Acme.Invoices but not
+// Work.Acme.EU.Invoices.
+func (o *Operations) findLabelsByGlob(ctx context.Context, pattern string) ([]*analyzer.LabelTransformation, error) {
+	separator := o.config.ParseOptions.SourceSeparator()
+	segmentPattern := strings.ReplaceAll(pattern, separator, "/")
+	if _, err := path.Match(segmentPattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid label pattern '%s': %v", pattern, err)
+	}
+
+	transformations, err := o.findLabelsMatching(ctx, func(name string) bool {
+		// A label matches if it or any of its ancestors matches, so children come along
+		parts := strings.Split(name, separator)
+		for i := len(parts); i > 0; i-- {
+			if matched, _ := path.Match(segmentPattern, strings.Join(parts[:i], "/")); matched {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(transformations) == 0 {
+		return nil, fmt.Errorf("no period-separated labels match '%s': %w", pattern, ErrNothingToProcess)
+	}
+
+	o.printf("   Pattern %s matches %d labels:\n", pattern, len(transformations))
+	for _, transformation := range transformations {
+		o.printf("   - %s\n", transformation.OriginalLabel)
+	}
+	return transformations, nil
+}
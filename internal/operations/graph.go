@@ -0,0 +1,113 @@
+package operations
+
+import (
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeDependencyGraph writes the transformations as a Graphviz DOT graph. Every label points at
+// the parent path it needs, and labels are grouped into the depth levels fix processes in order.
+// Labels claiming the same target share one node, drawn as a collision.
+func writeDependencyGraph(w io.Writer, result *analyzer.AnalysisResult) error {
+	byTarget := make(map[string][]*analyzer.LabelTransformation, len(result.Transformations))
+	levels := make(map[int][]string)
+	for _, transformation := range result.Transformations {
+		target := transformation.NestedStructure
+		if len(byTarget[target]) == 0 {
+			depth := len(transformation.HierarchyParts)
+			levels[depth] = append(levels[depth], target)
+		}
+		byTarget[target] = append(byTarget[target], transformation)
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph labels {\n")
+	b.WriteString("  rankdir=BT;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	var depths []int
+	for depth := range levels {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for order, depth := range depths {
+		targets := levels[depth]
+		sort.Strings(targets)
+		fmt.Fprintf(&b, "  subgraph level_%d {\n    rank=same;\n", depth)
+		for _, target := range targets {
+			sources := byTarget[target]
+			sort.Slice(sources, func(i, j int) bool { return sources[i].OriginalLabel < sources[j].OriginalLabel })
+			var names []string
+			messages := 0
+			for _, transformation := range sources {
+				names = append(names, transformation.OriginalLabel)
+				messages += transformation.MessageCount
+			}
+			label := fmt.Sprintf("%s\n→ %s\nstep %d, %d messages", strings.Join(names, "\n"), target, order+1, messages)
+			if len(sources) > 1 {
+				fmt.Fprintf(&b, "    %s [label=%s, color=red];\n", dotQuote(target), dotQuote(label+"\n(collision)"))
+			} else {
+				fmt.Fprintf(&b, "    %s [label=%s];\n", dotQuote(target), dotQuote(label))
+			}
+		}
+		b.WriteString("  }\n")
+	}
+
+	// Parents no transformation produces either exist already or are created by Gmail
+	parents := make(map[string]bool)
+	var edges []string
+	for target, sources := range byTarget {
+		transformation := sources[0] // Every source of a target needs the same parents
+		if len(transformation.RequiredParents) == 0 {
+			continue
+		}
+		parent := transformation.RequiredParents[len(transformation.RequiredParents)-1]
+		edges = append(edges, fmt.Sprintf("  %s -> %s;\n", dotQuote(target), dotQuote(parent)))
+		for _, required := range transformation.RequiredParents {
+			if len(byTarget[required]) == 0 {
+				parents[required] = true
+			}
+		}
+	}
+
+	var parentNames []string
+	for parent := range parents {
+		parentNames = append(parentNames, parent)
+	}
+	sort.Strings(parentNames)
+	for _, parent := range parentNames {
+		if _, exists := result.ExistingLabels[parent]; exists {
+			fmt.Fprintf(&b, "  %s [label=%s, style=filled, fillcolor=lightgrey];\n", dotQuote(parent), dotQuote(parent+"\n(exists)"))
+		} else {
+			fmt.Fprintf(&b, "  %s [label=%s, style=dashed];\n", dotQuote(parent), dotQuote(parent+"\n(created by Gmail)"))
+		}
+	}
+	// Parents' own parents, so chains of created parents stay connected
+	for _, parent := range parentNames {
+		if i := strings.LastIndex(parent, "/"); i > 0 {
+			edges = append(edges, fmt.Sprintf("  %s -> %s;\n", dotQuote(parent), dotQuote(parent[:i])))
+		}
+	}
+
+	sort.Strings(edges)
+	for _, edge := range edges {
+		b.WriteString(edge)
+	}
+	b.WriteString("}\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write dependency graph: %v", err)
+	}
+	return nil
+}
+
+// dotQuote quotes s as a DOT string, keeping newlines as line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
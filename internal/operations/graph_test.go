@@ -0,0 +1,22 @@
+package operations
+
+import (
+	"gmail-label-fixer/internal/analyzer"
+	"strings"
+	"testing"
+)
+
+func TestWriteDependencyGraphDrawsCollisions(t *testing.T) {
+	result := &analyzer.AnalysisResult{
+		Transformations: analyzer.BuildHierarchyMap([]string{"Work.Acme", "INBOX.Work.Acme"}),
+	}
+	var b strings.Builder
+	if err := writeDependencyGraph(&b, result); err != nil {
+		t.Fatalf("writeDependencyGraph: %v", err)
+	}
+
+	graph := b.String()
+	if !strings.Contains(graph, `INBOX.Work.Acme\nWork.Acme\n→ Work/Acme`) || !strings.Contains(graph, "(collision)") {
+		t.Errorf("collision on Work/Acme not drawn:\n%s", graph)
+	}
+}
@@ -0,0 +1,70 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readLabelList reads one label name per line, ignoring blank lines and # comments
+func readLabelList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+	return names, nil
+}
+
+// FixFromFile fixes the labels listed in a file, one name per line, and all their children.
+// Listed labels that aren't found are skipped with a warning.
+func (o *Operations) FixFromFile(ctx context.Context, path string) (*Result, error) {
+	names, err := readLabelList(path)
+	if err != nil {
+		return nil, err
+	}
+	o.printf("🔧 Fixing %d labels listed in %s\n", len(names), path)
+
+	separator := o.config.ParseOptions.SourceSeparator()
+	matched := make(map[string]bool, len(names))
+	transformations, err := o.findLabelsMatching(ctx, func(name string) bool {
+		found := false
+		for _, listed := range names {
+			if name == listed || strings.HasPrefix(name, listed+separator) {
+				matched[listed] = true
+				found = true
+			}
+		}
+		return found
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listed := range names {
+		if !matched[listed] {
+			o.printf("   ⚠️  Skipping %s: not found or is not period-separated\n", listed)
+		}
+	}
+	if len(transformations) == 0 {
+		return nil, fmt.Errorf("none of the labels listed in %s were found: %w", path, ErrNothingToProcess)
+	}
+	o.printf("   %d labels to fix, including children\n", len(transformations))
+
+	return o.fixSubtree(ctx, transformations)
+}
@@ -0,0 +1,34 @@
+package operations
+
+import (
+	"context"
+	"gmail-label-fixer/internal/analyzer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestFixFromFileRefusesFlattenCollisions(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "work", Name: "Work.Acme", Type: "user"},
+		{Id: "home", Name: "Home.Acme", Type: "user"},
+	})
+	path := filepath.Join(t.TempDir(), "labels.txt")
+	if err := os.WriteFile(path, []byte("Work\nHome\n"), 0600); err != nil {
+		t.Fatalf("writing input file: %v", err)
+	}
+
+	ops := newTestOperations(fake, func(config *Config) {
+		config.ParseOptions = analyzer.ParseOptions{FlattenTop: 1}
+	})
+	_, err := ops.FixFromFile(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "flattened names collide") {
+		t.Fatalf("FixFromFile error = %v, want the flattened collision refused", err)
+	}
+	if calls := fake.Calls["RenameLabel"] + fake.Calls["RenameLabelIfMatches"]; calls != 0 {
+		t.Errorf("renamed %d labels before refusing, want none", calls)
+	}
+}
@@ -0,0 +1,384 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"os"
+	"time"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+const (
+	DefaultJournalFile = "fixer-journal.json" // Default location of the rename journal
+)
+
+// JournalEntry records a single successful rename so it can be reverted later
+type JournalEntry struct {
+	OriginalID   string    `json:"originalID"`
+	OriginalName string    `json:"originalName"`
+	NewName      string    `json:"newName"`
+	RenamedAt    time.Time `json:"renamedAt"`
+
+	// MergedInto is the ID of the existing label MessageIDs were moved into before the original
+	// label was deleted; empty for renames
+	MergedInto string   `json:"mergedInto,omitempty"`
+	MessageIDs []string `json:"messageIDs,omitempty"`
+
+	// CopiedInto is the ID of the label --preserve-original added MessageIDs to, leaving the
+	// original label in place; CreatedCopy tells whether that label was created for the copy
+	CopiedInto  string `json:"copiedInto,omitempty"`
+	CreatedCopy bool   `json:"createdCopy,omitempty"`
+}
+
+// JournalRun groups the renames performed by a single fix invocation
+type JournalRun struct {
+	StartedAt time.Time      `json:"startedAt"`
+	Entries   []JournalEntry `json:"entries"`
+}
+
+// Journal is the on-disk history of fix runs, most recent run last
+type Journal struct {
+	Runs []*JournalRun `json:"runs"`
+
+	path    string
+	current *JournalRun
+}
+
+// loadJournal reads the journal at path, returning an empty journal if the file does not exist yet
+func loadJournal(path string) (*Journal, error) {
+	journal := &Journal{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return journal, nil
+		}
+		return nil, fmt.Errorf("unable to read journal %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("unable to parse journal %s: %v", path, err)
+	}
+	return journal, nil
+}
+
+// save writes the journal back to disk
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode journal: %v", err)
+	}
+
+	if err := os.WriteFile(j.path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write journal %s: %v", j.path, err)
+	}
+	return nil
+}
+
+// record appends a rename to the current run, starting a new run on first use, and persists it immediately
+func (j *Journal) record(entry JournalEntry) error {
+	if j.current == nil {
+		j.current = &JournalRun{StartedAt: time.Now()}
+		j.Runs = append(j.Runs, j.current)
+	}
+	j.current.Entries = append(j.current.Entries, entry)
+	return j.save()
+}
+
+// resumeLastRun makes later records extend the most recent run instead of starting a new one,
+// so undo reverts a resumed migration as a whole
+func (j *Journal) resumeLastRun() {
+	if len(j.Runs) > 0 {
+		j.current = j.Runs[len(j.Runs)-1]
+	}
+}
+
+// skipResumed drops transformations the journal shows were already renamed. Entries match a
+// transformation by label ID or, for a label added again under the old name, by that name. A
+// transformation is only skipped once the journaled rename is verified to be in effect.
+func (o *Operations) skipResumed(transformations []*analyzer.LabelTransformation, existingLabels map[string]*gmailAPI.Label) ([]*analyzer.LabelTransformation, error) {
+	journal, err := loadJournal(o.config.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	o.journalMu.Lock()
+	journal.resumeLastRun()
+	o.journal = journal
+	o.journalMu.Unlock()
+
+	renamed := make(map[string]JournalEntry)
+	renamedFrom := make(map[string]JournalEntry)
+	for _, run := range journal.Runs {
+		for _, entry := range run.Entries {
+			renamed[entry.OriginalID] = entry
+			renamedFrom[entry.OriginalName] = entry
+		}
+	}
+	o.printf("↪️  Resuming: journal %s records %d renamed labels\n", o.config.JournalPath, len(renamed))
+
+	var remaining []*analyzer.LabelTransformation
+	for _, transformation := range transformations {
+		entry, wasRenamed := renamed[transformation.OriginalID]
+		if !wasRenamed {
+			entry, wasRenamed = renamedFrom[transformation.OriginalLabel]
+		}
+		if !wasRenamed {
+			remaining = append(remaining, transformation)
+			continue
+		}
+
+		if !renameInEffect(entry, existingLabels) {
+			o.printf("   ⚠️  %s was renamed to %s in a previous run but exists under its old name again, processing it\n", transformation.OriginalLabel, entry.NewName)
+			remaining = append(remaining, transformation)
+			continue
+		}
+
+		o.printf("   ⏭️  Skipping %s (already renamed to %s)\n", transformation.OriginalLabel, entry.NewName)
+	}
+
+	return remaining, nil
+}
+
+// renameInEffect reports whether a journaled rename still holds: the new name exists on the label
+// that was renamed, or on the label it was merged into. Gmail keeps IDs across renames, so the
+// original label then no longer carries its old name.
+func renameInEffect(entry JournalEntry, existingLabels map[string]*gmailAPI.Label) bool {
+	target, exists := existingLabels[entry.NewName]
+	if !exists {
+		return false
+	}
+	switch {
+	case entry.MergedInto != "":
+		return target.Id == entry.MergedInto
+	case entry.CopiedInto != "":
+		return target.Id == entry.CopiedInto
+	}
+	return target.Id == entry.OriginalID
+}
+
+// recordRename appends a successful rename to the journal, if journaling is enabled
+func (o *Operations) recordRename(originalID, originalName, newName string) {
+	o.recordEntry(JournalEntry{
+		OriginalID:   originalID,
+		OriginalName: originalName,
+		NewName:      newName,
+		RenamedAt:    time.Now(),
+	})
+}
+
+// recordMerge appends a merge of messageIDs into target to the journal, so undo can recreate
+// the deleted label and move the messages back
+func (o *Operations) recordMerge(originalID, originalName string, target *gmailAPI.Label, messageIDs []string) {
+	o.recordEntry(JournalEntry{
+		OriginalID:   originalID,
+		OriginalName: originalName,
+		NewName:      target.Name,
+		RenamedAt:    time.Now(),
+		MergedInto:   target.Id,
+		MessageIDs:   messageIDs,
+	})
+}
+
+// recordCopy appends a --preserve-original copy of messageIDs into target to the journal, so undo
+// can delete the label it created or take target off the copied messages again
+func (o *Operations) recordCopy(originalID, originalName string, target *gmailAPI.Label, messageIDs []string, created bool) {
+	o.recordEntry(JournalEntry{
+		OriginalID:   originalID,
+		OriginalName: originalName,
+		NewName:      target.Name,
+		RenamedAt:    time.Now(),
+		MessageIDs:   messageIDs,
+		CopiedInto:   target.Id,
+		CreatedCopy:  created,
+	})
+}
+
+// recordEntry appends an entry to the journal, if journaling is enabled
+func (o *Operations) recordEntry(entry JournalEntry) {
+	if o.config.JournalPath == "" {
+		return
+	}
+
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+
+	if o.journal == nil {
+		journal, err := loadJournal(o.config.JournalPath)
+		if err != nil {
+			o.printf("   ⚠️  Warning: Could not open journal, rename will not be undoable: %v\n", err)
+			return
+		}
+		o.journal = journal
+	}
+
+	if err := o.journal.record(entry); err != nil {
+		o.printf("   ⚠️  Warning: Could not write journal entry: %v\n", err)
+	}
+}
+
+// Undo reverts the renames of the most recent fix run recorded in the journal
+func (o *Operations) Undo(ctx context.Context) error {
+	journal, err := loadJournal(o.config.JournalPath)
+	if err != nil {
+		return err
+	}
+
+	if len(journal.Runs) == 0 {
+		o.printf("✅ Nothing to undo: no runs recorded in %s\n", o.config.JournalPath)
+		return nil
+	}
+
+	run := journal.Runs[len(journal.Runs)-1]
+	total := len(run.Entries)
+	o.printf("↩️  Undoing %d renames from run started %s\n", total, run.StartedAt.Format(time.RFC3339))
+
+	labels, err := o.client.GetAllLabels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+	labelsByID := make(map[string]*gmailAPI.Label)
+	for _, label := range labels {
+		labelsByID[label.Id] = label
+	}
+
+	// Revert in reverse order so children are restored before their parents
+	reverted, failed := 0, 0
+	var remaining []JournalEntry
+	for i := total - 1; i >= 0; i-- {
+		entry := run.Entries[i]
+		if ctx.Err() != nil {
+			// Keep unattempted entries so the undo can be resumed
+			remaining = append(run.Entries[:i+1:i+1], remaining...)
+			break
+		}
+		o.printf("\n[%d/%d] Reverting: %s → %s\n", total-i, total, entry.NewName, entry.OriginalName)
+
+		if entry.CopiedInto != "" {
+			if err := o.undoCopy(ctx, entry); err != nil {
+				o.printf("❌ Failed: %v\n", err)
+				failed++
+				remaining = append([]JournalEntry{entry}, remaining...)
+				continue
+			}
+			reverted++
+			o.printf("✅ Reverted: removed the copy in %s, %s was kept\n", entry.NewName, entry.OriginalName)
+			continue
+		}
+		if entry.MergedInto != "" {
+			if err := o.undoMerge(ctx, entry, labels); err != nil {
+				o.printf("❌ Failed: %v\n", err)
+				failed++
+				remaining = append([]JournalEntry{entry}, remaining...)
+				continue
+			}
+			reverted++
+			o.printf("✅ Reverted: %s → %s\n", entry.NewName, entry.OriginalName)
+			continue
+		}
+
+		current, exists := labelsByID[entry.OriginalID]
+		if !exists {
+			o.printf("   ⚠️  Skipping: label %s no longer exists\n", entry.OriginalID)
+			continue
+		}
+		if current.Name != entry.NewName {
+			o.printf("   ⚠️  Skipping: label was modified since the fix (now named '%s')\n", current.Name)
+			continue
+		}
+
+		err := o.retryWithBackoff(ctx, func() error {
+			_, err := o.client.RenameLabel(ctx, entry.OriginalID, entry.OriginalName)
+			return err
+		})
+		if err != nil {
+			o.printf("❌ Failed: %v\n", err)
+			failed++
+			// Keep the entry so a later undo can try again
+			remaining = append([]JournalEntry{entry}, remaining...)
+			continue
+		}
+
+		o.withRateLimit(ctx)
+
+		reverted++
+		o.logger().Info("label reverted", "label_id", entry.OriginalID, "from", entry.NewName, "to", entry.OriginalName)
+		o.printf("✅ Reverted: %s → %s\n", entry.NewName, entry.OriginalName)
+	}
+
+	if len(remaining) > 0 {
+		run.Entries = remaining
+	} else {
+		journal.Runs = journal.Runs[:len(journal.Runs)-1]
+	}
+	if err := journal.save(); err != nil {
+		return err
+	}
+
+	o.printf("\n🎉 Undo completed! Reverted %d/%d labels successfully.\n", reverted, total)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d reverts failed: %w", failed, total, ErrLabelsFailed)
+	}
+	return nil
+}
+
+// undoCopy reverts a --preserve-original copy: a label created for the copy is deleted, and an
+// existing label is taken off the messages copied into it
+func (o *Operations) undoCopy(ctx context.Context, entry JournalEntry) error {
+	if entry.CreatedCopy {
+		err := o.retryWithBackoff(ctx, func() error {
+			return o.client.DeleteLabel(ctx, entry.CopiedInto)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete copied label: %w", err)
+		}
+	} else if len(entry.MessageIDs) > 0 {
+		// The client retries each chunk itself
+		if err := o.client.BatchModifyMessages(ctx, entry.MessageIDs, nil, []string{entry.CopiedInto}); err != nil {
+			return fmt.Errorf("failed to remove %s from the copied messages: %w", entry.NewName, err)
+		}
+	}
+	o.withRateLimit(ctx)
+
+	o.logger().Info("label copy reverted", "label_id", entry.CopiedInto, "from", entry.OriginalName, "to", entry.NewName, "deleted", entry.CreatedCopy, "messages", len(entry.MessageIDs))
+	return nil
+}
+
+// undoMerge recreates a label deleted by a merge and moves its messages back out of the label
+// they were merged into
+func (o *Operations) undoMerge(ctx context.Context, entry JournalEntry, labels []*gmailAPI.Label) error {
+	for _, label := range labels {
+		if label.Name == entry.OriginalName {
+			return fmt.Errorf("a label named '%s' exists again (ID: %s)", entry.OriginalName, label.Id)
+		}
+	}
+
+	var restored *gmailAPI.Label
+	err := o.retryWithBackoff(ctx, func() error {
+		var err error
+		restored, err = o.client.CreateLabelWithVisibility(ctx, entry.OriginalName, "labelShow", "show")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to recreate label: %w", err)
+	}
+	o.withRateLimit(ctx)
+
+	if len(entry.MessageIDs) > 0 {
+		// The client retries each chunk itself
+		if err := o.client.BatchModifyMessages(ctx, entry.MessageIDs, []string{restored.Id}, []string{entry.MergedInto}); err != nil {
+			return fmt.Errorf("recreated %s but failed to move its messages back: %v", entry.OriginalName, err)
+		}
+		o.withRateLimit(ctx)
+	}
+
+	o.logger().Info("label merge reverted", "label_id", restored.Id, "from", entry.NewName, "to", entry.OriginalName, "messages_moved", len(entry.MessageIDs))
+	return nil
+}
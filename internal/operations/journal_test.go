@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// journalRename writes a journal at a temporary path recording one rename from a previous run
+func journalRename(t *testing.T, originalID, originalName, newName string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultJournalFile)
+	journal, err := loadJournal(path)
+	if err != nil {
+		t.Fatalf("loadJournal: %v", err)
+	}
+	entry := JournalEntry{OriginalID: originalID, OriginalName: originalName, NewName: newName, RenamedAt: time.Now()}
+	if err := journal.record(entry); err != nil {
+		t.Fatalf("recording journal entry: %v", err)
+	}
+	return path
+}
+
+func TestFixAllLabelsResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      []*gmailAPI.Label
+		wantRenames int
+		wantOutput  string
+	}{
+		{
+			name: "rename took, old name added again",
+			labels: []*gmailAPI.Label{
+				{Id: "acme", Name: "Work/Acme", Type: "user"},
+				{Id: "again", Name: "Work.Acme", Type: "user"},
+				{Id: "beta", Name: "Work.Beta", Type: "user"},
+			},
+			wantRenames: 1,
+			wantOutput:  "Skipping Work.Acme (already renamed to Work/Acme)",
+		},
+		{
+			name: "rename reverted",
+			labels: []*gmailAPI.Label{
+				{Id: "acme", Name: "Work.Acme", Type: "user"},
+				{Id: "beta", Name: "Work.Beta", Type: "user"},
+			},
+			wantRenames: 2,
+			wantOutput:  "exists under its old name again, processing it",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeService(tt.labels)
+			path := journalRename(t, "acme", "Work.Acme", "Work/Acme")
+			var output strings.Builder
+
+			ops := newTestOperations(fake, func(config *Config) {
+				config.JournalPath = path
+				config.Resume = true
+				config.Output = &output
+			})
+			if _, err := ops.FixAllLabels(context.Background()); err != nil {
+				t.Fatalf("FixAllLabels: %v", err)
+			}
+			if renames := fake.Calls["RenameLabel"] + fake.Calls["RenameLabelIfMatches"]; renames != tt.wantRenames {
+				t.Errorf("renamed %d labels, want %d", renames, tt.wantRenames)
+			}
+			if !strings.Contains(output.String(), tt.wantOutput) {
+				t.Errorf("output does not contain %q:\n%s", tt.wantOutput, output.String())
+			}
+		})
+	}
+}
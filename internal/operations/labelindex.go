@@ -0,0 +1,148 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/gmail"
+	"maps"
+	"strings"
+	"sync"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// labelIndex is a run's view of the mailbox's labels by name. It is listed once and then kept up
+// to date with the run's own renames, creations and deletions, so lookups cost no API calls.
+type labelIndex struct {
+	mu     sync.Mutex
+	byName map[string]*gmailAPI.Label // nil until first listed
+
+	// created holds the parent names that did not exist before this run
+	created map[string]bool
+	// unlisted holds parents Gmail created while renaming a child, whose IDs are only known
+	// after listing the labels again
+	unlisted map[string]bool
+}
+
+// findLabel looks up an existing label by name the way Gmail displays names, ignoring
+// surrounding whitespace. The labels are listed on the first lookup only.
+func (o *Operations) findLabel(ctx context.Context, name string) (*gmailAPI.Label, bool, error) {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.byName == nil || index.unlisted[gmail.NormalizeLabelName(name)] {
+		if err := o.listLabels(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if label, exists := index.byName[name]; exists {
+		return label, true, nil
+	}
+	for existing, label := range index.byName {
+		if gmail.NormalizeLabelName(existing) == gmail.NormalizeLabelName(name) {
+			return label, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// existingLabels returns a copy of the run's labels by name, listing them on first use
+func (o *Operations) existingLabels(ctx context.Context) (map[string]*gmailAPI.Label, error) {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.byName == nil || len(index.unlisted) > 0 {
+		if err := o.listLabels(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return maps.Clone(index.byName), nil
+}
+
+// listLabels (re)builds the index from the mailbox; the caller must hold the index lock
+func (o *Operations) listLabels(ctx context.Context) error {
+	var labels []*gmailAPI.Label
+	err := o.retryWithBackoff(ctx, func() error {
+		var err error
+		labels, err = o.client.GetAllLabels(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+
+	index := &o.labels
+	index.byName = make(map[string]*gmailAPI.Label, len(labels))
+	for _, label := range labels {
+		index.byName[label.Name] = label
+	}
+	index.unlisted = nil
+	return nil
+}
+
+// labelRenamed updates the index after a rename. Missing ancestors of the new name are
+// created by Gmail, so they are remembered as created by this run.
+func (o *Operations) labelRenamed(oldName string, renamed *gmailAPI.Label) {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.byName == nil {
+		return // Nothing listed yet, the first lookup sees the rename
+	}
+	delete(index.byName, oldName)
+	index.byName[renamed.Name] = renamed
+
+	parts := strings.Split(renamed.Name, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if _, exists := index.byName[parent]; exists {
+			continue
+		}
+		if index.created == nil {
+			index.created = make(map[string]bool)
+		}
+		if index.unlisted == nil {
+			index.unlisted = make(map[string]bool)
+		}
+		index.created[parent] = true
+		index.unlisted[parent] = true
+	}
+}
+
+// labelCreated adds a label this run created to the index
+func (o *Operations) labelCreated(label *gmailAPI.Label) {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.created == nil {
+		index.created = make(map[string]bool)
+	}
+	index.created[label.Name] = true
+	if index.byName != nil {
+		index.byName[label.Name] = label
+	}
+}
+
+// labelDeleted drops a label this run deleted from the index
+func (o *Operations) labelDeleted(name string) {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	delete(index.byName, name)
+}
+
+// createdThisRun reports whether a label only exists because this run created it, either
+// directly or as a parent Gmail added for a renamed child
+func (o *Operations) createdThisRun(name string) bool {
+	index := &o.labels
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	return index.created[name]
+}
@@ -0,0 +1,56 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// List prints period-separated labels without counting messages, using a single API call.
+// Only labels with at least minDepth period-separated segments are shown.
+func (o *Operations) List(ctx context.Context, minDepth int) error {
+	analysis, err := o.client.FindPeriodSeparatedLabelsWithAnalysis(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+
+	var processable []*gmailAPI.Label
+	for _, label := range analysis.ProcessableLabels {
+		if segmentCount(label.Name) >= minDepth {
+			processable = append(processable, label)
+		}
+	}
+	sort.Slice(processable, func(i, j int) bool {
+		return processable[i].Name < processable[j].Name
+	})
+
+	o.printf("\n📋 Period-separated labels (%d):\n", len(processable))
+	for _, label := range processable {
+		o.printf("   - %s\n", label.Name)
+	}
+
+	var skipped []string
+	for _, skippedLabel := range analysis.SkippedLabels {
+		if segmentCount(skippedLabel.Label.Name) >= minDepth {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", skippedLabel.Label.Name, skippedLabel.Reason))
+		}
+	}
+	sort.Strings(skipped)
+
+	if len(skipped) > 0 {
+		o.printf("\n⏭️  Skipped labels (%d):\n", len(skipped))
+		for _, label := range skipped {
+			o.printf("   - %s\n", label)
+		}
+	}
+
+	return nil
+}
+
+// segmentCount returns the number of period-separated parts in a label name
+func segmentCount(labelName string) int {
+	return len(strings.Split(labelName, "."))
+}
@@ -0,0 +1,62 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+const (
+	OnConflictFail  = "fail"  // Abort the rename when the target label already exists (default)
+	OnConflictMerge = "merge" // Move messages into the existing target and delete the source label
+)
+
+// OnConflictModes lists the supported values for the --on-conflict flag
+var OnConflictModes = []string{OnConflictFail, OnConflictMerge}
+
+// mergeIntoExisting relabels every message from the source label onto the existing target
+// label and then deletes the source label
+func (o *Operations) mergeIntoExisting(ctx context.Context, transformation *analyzer.LabelTransformation, target *gmailAPI.Label) error {
+	o.detailf("   🔀 Merging label: %s → existing %s (ID: %s)\n", transformation.OriginalLabel, target.Name, target.Id)
+
+	var messageIDs []string
+	err := o.retryWithBackoff(ctx, func() error {
+		var err error
+		messageIDs, err = o.client.GetMessagesWithLabel(ctx, transformation.OriginalID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list messages to merge: %w", err)
+	}
+
+	if len(messageIDs) == 0 {
+		o.detailf("   ℹ️  Source label has no messages, nothing to move\n")
+	} else {
+		// Relabel in batches; the client retries each chunk itself
+		if err := o.client.BatchModifyMessages(ctx, messageIDs, []string{target.Id}, []string{transformation.OriginalID}); err != nil {
+			return fmt.Errorf("failed to move messages: %w", err)
+		}
+		o.withRateLimit(ctx)
+	}
+	moved := len(messageIDs)
+	o.analyzer.ForgetCount(target.Id)
+
+	err = o.retryWithBackoff(ctx, func() error {
+		return o.client.DeleteLabel(ctx, transformation.OriginalID)
+	})
+	if err != nil {
+		return fmt.Errorf("moved %d messages but failed to delete source label: %w", moved, err)
+	}
+	o.labelDeleted(transformation.OriginalLabel)
+	o.recordMerge(transformation.OriginalID, transformation.OriginalLabel, target, messageIDs)
+
+	o.withRateLimit(ctx)
+
+	o.logger().Info("label merged", "label_id", transformation.OriginalID, "from", transformation.OriginalLabel, "target_id", target.Id, "to", target.Name, "messages_moved", moved)
+
+	o.detailf("   ✅ Merged into: %s (ID: %s)\n", target.Name, target.Id)
+	o.detailf("   📧 Moved %d messages and deleted %s\n", moved, transformation.OriginalLabel)
+	return nil
+}
@@ -0,0 +1,41 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// printAmbiguous lists existing labels whose names look identical in Gmail
+func (o *Operations) printAmbiguous(ambiguous []string) {
+	o.println("⚠️  Labels that look identical in Gmail (renaming into them is unpredictable):")
+	for _, warning := range ambiguous {
+		o.printf("   - %s\n", warning)
+	}
+}
+
+// checkAmbiguousNames warns about existing labels that look identical in Gmail, and refuses to
+// continue when Config.StrictNames is set
+func (o *Operations) checkAmbiguousNames(existingLabels map[string]*gmailAPI.Label) error {
+	ambiguous := analyzer.FindAmbiguousLabels(existingLabels)
+	if len(ambiguous) == 0 {
+		return nil
+	}
+
+	o.printAmbiguous(ambiguous)
+	if o.config.StrictNames {
+		return fmt.Errorf("%d groups of labels look identical in Gmail (--strict-names)", len(ambiguous))
+	}
+	return nil
+}
+
+// checkAmbiguousLabels lists every label and runs checkAmbiguousNames against them
+func (o *Operations) checkAmbiguousLabels(ctx context.Context) error {
+	labels, err := o.client.GetAllLabels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+	return o.checkAmbiguousNames(analyzer.IndexLabelsByName(labels))
+}
@@ -1,9 +1,14 @@
 package operations
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"gmail-label-fixer/internal/analyzer"
 	"gmail-label-fixer/internal/gmail"
+	"io"
+	"log/slog"
 	"math"
 	"math/rand"
 	"net/http"
@@ -11,6 +16,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
@@ -26,69 +33,253 @@ const (
 )
 
 type Config struct {
-	RateLimitDelay int // Delay between API calls in milliseconds
-	MaxRetries     int // Maximum retries for rate-limited requests
+	RateLimitDelay     int          // Delay between API calls in milliseconds
+	MaxRetries         int          // Maximum retries for rate-limited requests
+	JournalPath        string       // File recording successful renames for undo (empty disables journaling)
+	Output             io.Writer    // Destination for status messages (defaults to os.Stdout)
+	Input              io.Reader    // Source of interactive answers (defaults to os.Stdin)
+	AssumeYes          bool         // Skip confirmation prompts
+	Concurrency        int          // Number of renames to run in parallel within a hierarchy level
+	AnalyzeConcurrency int          // Number of message counts to fetch in parallel during analysis
+	OnConflict         string       // What to do when the target label already exists: OnConflictFail or OnConflictMerge
+	Logger             *slog.Logger // Structured event log (defaults to discarding events)
+	Resume             bool         // Skip labels the journal shows were already renamed, continuing the last run
+	HiddenParents      bool         // Create missing parent labels hidden instead of letting Gmail show them
+	MinMessages        int          // Leave labels with fewer messages than this untouched
+	DeleteEmpty        bool         // Delete labels without messages instead of renaming them
+	NoColor            bool         // Disable colored status output on terminals
+	Plain              bool         // Replace status emoji with ASCII tags like [OK] and [ERR]
+	ParseOptions       analyzer.ParseOptions
+	DelayJitter        int    // Random extra delay of up to this many milliseconds between API calls
+	AdaptiveRate       bool   // Tune the delay between calls from observed 429s instead of using RateLimitDelay
+	Quiet              bool   // Print only errors, to stderr
+	ReportPath         string // File the post-run summary is written to (empty disables the report)
+	ReportFormat       string // ReportJSON or ReportMarkdown
+	Step               bool   // Ask before applying each transformation
+	StrictNames        bool   // Refuse to fix while existing labels have names that look identical
+	Timings            bool   // List the slowest labels after a batch fix
+	FailFast           bool   // Stop a batch fix at the first failed label instead of continuing
+	// CountMode counts messages or threads (analyzer.CountModeMessages or CountModeThreads)
+	CountMode string
+	// ConfirmThreshold skips the fix confirmation when neither the labels nor their messages
+	// number more than it (0 always asks)
+	ConfirmThreshold int
+	// Preview makes fix show its plan and stop without changing any labels
+	Preview bool
+	// PreserveOriginal creates each nested label and copies the messages into it, keeping the
+	// original label, instead of renaming in place
+	PreserveOriginal bool
+	// MaxTotalBackoff caps the time spent waiting between retries over the whole run (0 for no limit)
+	MaxTotalBackoff time.Duration
+	ActiveSince     time.Duration // Only process labels with a message received within this long (0 processes all)
+	// CountCache reuses message counts from a recent run for planning (nil fetches every count)
+	CountCache *analyzer.CountCache
 }
 
 type Operations struct {
-	client   *gmail.Client
+	client   gmail.LabelService
 	analyzer *analyzer.Analyzer
 	config   *Config
+	journal  *Journal
+
+	journalMu sync.Mutex
+	parentsMu sync.Mutex
+	progress  *progressBar
+	rate      *adaptiveRate // nil unless Config.AdaptiveRate is set
+	quiet     *quietWriter  // nil unless Config.Quiet is set
+	step      *stepper      // nil unless Config.Step is set
+	answers   *bufio.Reader // interactive input, created on the first prompt
+
+	backoffMu    sync.Mutex
+	backoffTotal time.Duration // time spent waiting between retries, across all operations
+
+	copiedMessages atomic.Int64 // messages copied under Config.PreserveOriginal
+
+	labels labelIndex // existing labels by name, listed once per run
 }
 
-func NewOperations(client *gmail.Client) *Operations {
-	return &Operations{
-		client:   client,
-		analyzer: analyzer.NewAnalyzer(client),
-		config: &Config{
-			RateLimitDelay: defaultRateLimitDelay,
-			MaxRetries:     defaultMaxRetries,
-		},
-	}
+func NewOperations(client gmail.LabelService) *Operations {
+	return NewOperationsWithConfig(client, &Config{
+		RateLimitDelay: defaultRateLimitDelay,
+		MaxRetries:     defaultMaxRetries,
+		JournalPath:    DefaultJournalFile,
+		Concurrency:    1,
+		OnConflict:     OnConflictFail,
+	})
 }
 
-func NewOperationsWithConfig(client *gmail.Client, config *Config) *Operations {
-	return &Operations{
+func NewOperationsWithConfig(client gmail.LabelService, config *Config) *Operations {
+	o := &Operations{
 		client:   client,
-		analyzer: analyzer.NewAnalyzer(client),
+		analyzer: analyzer.NewAnalyzerWithOptions(client, config.ParseOptions),
 		config:   config,
 	}
+	if config.CountCache != nil {
+		o.analyzer.SetCountCache(config.CountCache)
+	}
+	if config.AnalyzeConcurrency > 1 {
+		o.analyzer.SetConcurrency(config.AnalyzeConcurrency)
+	}
+	if config.CountMode != "" {
+		o.analyzer.SetCountMode(config.CountMode)
+	}
+	if config.ActiveSince > 0 {
+		o.analyzer.SetActiveSince(time.Now().Add(-config.ActiveSince))
+	}
+	if config.AdaptiveRate {
+		o.rate = newAdaptiveRate()
+	}
+	if config.Quiet {
+		o.quiet = &quietWriter{out: os.Stderr}
+		if config.Plain {
+			o.quiet.out = NewPlainWriter(os.Stderr)
+		}
+	}
+	if config.Step {
+		o.step = &stepper{}
+	}
+	// Label listing happens inside the client, so it needs the same backoff as our own calls
+	if retrying, ok := client.(interface{ SetRetry(gmail.RetryFunc) }); ok {
+		retrying.SetRetry(o.retryWithBackoff)
+	}
+	return o
 }
 
-// withRateLimit applies rate limiting delay between operations
-func (o *Operations) withRateLimit() {
-	if o.config.RateLimitDelay > 0 {
-		time.Sleep(time.Duration(o.config.RateLimitDelay) * time.Millisecond)
+// output returns the writer status messages are sent to
+func (o *Operations) output() io.Writer {
+	if o.quiet != nil {
+		return o.quiet
+	}
+	out := o.config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	if o.config.Plain {
+		return NewPlainWriter(out)
+	}
+	return out
+}
+
+// logger returns the structured event logger
+func (o *Operations) logger() *slog.Logger {
+	if o.config.Logger == nil {
+		return discardLogger
+	}
+	return o.config.Logger
+}
+
+// discardLogger drops every event when no logger is configured
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// printf writes a formatted status message, above the progress bar when one is active
+func (o *Operations) printf(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	if o.useColor() {
+		text = colorize(text)
+	}
+	if o.progress != nil {
+		o.progress.printAbove(text)
+		return
+	}
+	fmt.Fprint(o.output(), text)
+}
+
+// println writes a status message followed by a newline
+func (o *Operations) println(args ...interface{}) {
+	o.printf("%s", fmt.Sprintln(args...))
+}
+
+// detailf writes per-label progress details, which the progress bar replaces when active
+func (o *Operations) detailf(format string, args ...interface{}) {
+	if o.progress != nil {
+		return
+	}
+	o.printf(format, args...)
+}
+
+// withRateLimit applies rate limiting delay between operations, returning early if ctx is cancelled
+func (o *Operations) withRateLimit(ctx context.Context) {
+	delay := time.Duration(o.config.RateLimitDelay) * time.Millisecond
+	if o.rate != nil {
+		delay = o.rate.current()
+	}
+	if o.config.DelayJitter > 0 {
+		delay += time.Duration(rand.Intn(o.config.DelayJitter+1)) * time.Millisecond
+	}
+	if delay > 0 {
+		sleepContext(ctx, delay)
+	}
+}
+
+// withoutCancel detaches ctx from cancellation so in-flight work can complete after an
+// interrupt, while keeping any deadline from --timeout
+func withoutCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return detached, func() {}
+}
+
+// sleepContext waits for the given duration or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
 // retryWithBackoff performs an operation with exponential backoff for rate limits
-func (o *Operations) retryWithBackoff(operation func() error) error {
+func (o *Operations) retryWithBackoff(ctx context.Context, operation func() error) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= o.config.MaxRetries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff with jitter
-			baseDelay := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-			jitter := time.Duration(rand.Intn(jitterMaxMs)) * time.Millisecond
-			delay := baseDelay + jitter
-
-			if delay > maxBackoffDelay*time.Second {
-				delay = maxBackoffDelay * time.Second // Cap at maximum backoff delay
+			// Prefer the server's Retry-After hint, otherwise exponential backoff with jitter
+			delay, ok := retryAfterDelay(lastErr)
+			if !ok {
+				baseDelay := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
+				jitter := time.Duration(rand.Intn(jitterMaxMs)) * time.Millisecond
+				delay = baseDelay + jitter
+
+				if delay > maxBackoffDelay*time.Second {
+					delay = maxBackoffDelay * time.Second // Cap at maximum backoff delay
+				}
 			}
 
-			fmt.Printf("   ⏳ Rate limit hit, waiting %v before retry %d/%d...\n", delay, attempt, o.config.MaxRetries)
-			time.Sleep(delay)
+			if err := o.spendBackoff(delay); err != nil {
+				return fmt.Errorf("%w (last error: %v)", err, lastErr)
+			}
+			o.printf("   ⏳ Rate limit hit, waiting %v before retry %d/%d...\n", delay, attempt, o.config.MaxRetries)
+			o.logger().Warn("retrying after transient error", "attempt", attempt, "max_retries", o.config.MaxRetries, "delay", delay, "error", lastErr)
+			if err := sleepContext(ctx, delay); err != nil {
+				return fmt.Errorf("gave up waiting to retry: %w", err)
+			}
 		}
 
 		err := operation()
 		if err == nil {
+			if o.rate != nil {
+				o.rate.succeeded()
+			}
 			return nil // Success
 		}
 
 		lastErr = err
+		if o.rate != nil && isRateLimitError(err) {
+			delay := o.rate.throttled()
+			o.logger().Warn("throttled, raising delay between calls", "delay", delay)
+		}
 
 		// Check if this is a retryable error
+		if isConflictError(err) {
+			return fmt.Errorf("target name conflict — not retrying: %w", err)
+		}
 		if !isRetryableError(err) {
 			return err // Don't retry non-retryable errors
 		}
@@ -99,7 +290,38 @@ func (o *Operations) retryWithBackoff(operation func() error) error {
 		}
 	}
 
-	return fmt.Errorf("operation failed after %d retries: %v", o.config.MaxRetries, lastErr)
+	return fmt.Errorf("operation failed after %d retries: %w", o.config.MaxRetries, lastErr)
+}
+
+// retryAfterDelay extracts the wait requested by a Retry-After header on a Google API error.
+// The header may hold either a number of seconds or an HTTP date.
+func retryAfterDelay(err error) (time.Duration, bool) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Header == nil {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(apiErr.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// isConflictError reports whether err is a 409 or 412 from Gmail, which no retry can fix
+func isConflictError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && (apiErr.Code == http.StatusConflict || apiErr.Code == http.StatusPreconditionFailed)
 }
 
 // isRetryableError determines if an error should be retried
@@ -108,8 +330,14 @@ func isRetryableError(err error) bool {
 		return false
 	}
 
-	// Check for Google API errors
-	if apiErr, ok := err.(*googleapi.Error); ok {
+	// Cancellation and deadlines are deliberate, never retry them
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Check for Google API errors, including ones wrapped by the client
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
 		switch apiErr.Code {
 		case http.StatusTooManyRequests, // 429
 			http.StatusInternalServerError, // 500
@@ -117,6 +345,10 @@ func isRetryableError(err error) bool {
 			http.StatusServiceUnavailable,  // 503
 			http.StatusGatewayTimeout:      // 504
 			return true
+		case http.StatusBadRequest, // 400
+			http.StatusConflict,           // 409
+			http.StatusPreconditionFailed: // 412
+			return false // Permanent, whatever the message says
 		case http.StatusForbidden: // 403 - might be quota exceeded
 			return strings.Contains(strings.ToLower(apiErr.Message), "quota") ||
 				strings.Contains(strings.ToLower(apiErr.Message), "rate limit")
@@ -130,75 +362,271 @@ func isRetryableError(err error) bool {
 		strings.Contains(errStr, "temporary failure")
 }
 
-func (o *Operations) DryRun() error {
-	fmt.Println("🔍 Analyzing Gmail labels...")
+// DryRunOptions controls how the analysis results are presented
+type DryRunOptions struct {
+	Output string    // Output format: OutputTable, OutputTree, OutputJSON, OutputYAML or OutputCSV
+	Sort   SortOrder // Row order of the transformations table
+	Sample int       // Analyze only the first Sample labels by name (0 analyzes all)
+	// Results receives the transformations table or JSON/YAML document (defaults to os.Stdout)
+	Results     io.Writer
+	GroupByRoot bool // Render one table per top-level segment, with a message subtotal each
+	TreeDiff    bool // Emit the label trees before and after the fix as JSON instead of Output
+	// OnlyConflicts lists just the transformations CheckConflicts flags, hiding the clean ones
+	OnlyConflicts bool
+	// DebugGraph emits the label → parent dependency graph in Graphviz DOT format instead of Output
+	DebugGraph bool
+}
+
+// conflictingOnly returns the transformations with a conflict and how many clean ones were left out
+func (o *Operations) conflictingOnly(result *analyzer.AnalysisResult) (map[string]*analyzer.LabelTransformation, int) {
+	conflicting := o.analyzer.FindConflictingLabels(result.Transformations, result.ExistingLabels)
+	shown := make(map[string]*analyzer.LabelTransformation, len(conflicting))
+	for label, transformation := range result.Transformations {
+		if conflicting[label] {
+			shown[label] = transformation
+		}
+	}
+	return shown, len(result.Transformations) - len(shown)
+}
+
+// results returns the writer the analysis itself is rendered to
+func (opts DryRunOptions) results() io.Writer {
+	if opts.Results == nil {
+		return os.Stdout
+	}
+	return opts.Results
+}
 
-	result, err := o.analyzer.AnalyzeLabels()
+func (o *Operations) DryRun(ctx context.Context, opts DryRunOptions) error {
+	o.println("🔍 Analyzing Gmail labels...")
+
+	result, err := o.analyzer.AnalyzeLabelsSample(ctx, opts.Sample)
 	if err != nil {
 		return fmt.Errorf("analysis failed: %v", err)
 	}
 
+	if opts.TreeDiff {
+		return writeTreeDiff(opts.results(), result)
+	}
+	if opts.DebugGraph {
+		return writeDependencyGraph(opts.results(), result)
+	}
+
+	if IsMachineReadable(opts.Output) {
+		conflicts := o.analyzer.CheckConflicts(result.Transformations, result.ExistingLabels)
+		warnings := o.analyzer.FindNearDuplicates(result.Transformations, result.ExistingLabels)
+		warnings = append(warnings, analyzer.FindAmbiguousLabels(result.ExistingLabels)...)
+		warnings = append(warnings, o.analyzer.FindDependencyWarnings(result.Transformations, result.SkippedLabels)...)
+		for _, failure := range countFailures(result) {
+			warnings = append(warnings, "could not count messages of "+failure)
+		}
+		if opts.OnlyConflicts {
+			filtered := *result
+			filtered.Transformations, _ = o.conflictingOnly(result)
+			return writeAnalysis(opts.results(), opts.Output, &filtered, conflicts, warnings)
+		}
+		return writeAnalysis(opts.results(), opts.Output, result, conflicts, warnings)
+	}
+
 	if len(result.PeriodLabels) == 0 {
-		fmt.Println("✅ No period-separated labels found. Your labels are already properly structured!")
+		o.println("✅ No period-separated labels found. Your labels are already properly structured!")
 		return nil
 	}
 
-	fmt.Printf("\n📊 Found %d period-separated labels with %d total messages\n", len(result.PeriodLabels), result.TotalMessages)
-
-	// Show information about skipped system labels
-	if len(result.SkippedLabels) > 0 {
-		fmt.Printf("ℹ️  Skipped %d system labels (INBOX.Trash, INBOX.Sent, etc.)\n", len(result.SkippedLabels))
+	o.printf("\n📊 Found %d period-separated labels with %d total messages\n", len(result.PeriodLabels), result.TotalMessages)
+	if result.SampledFrom > 0 {
+		o.printf("🔬 Showing sample of %d of %d labels (the first by name)\n", len(result.PeriodLabels), result.SampledFrom)
+	}
+	if failures := countFailures(result); len(failures) > 0 {
+		o.printf("⚠️  Could not count messages of %d labels, shown as 0:\n", len(failures))
+		for _, failure := range failures {
+			o.printf("   - %s\n", failure)
+		}
 	}
 
+	// Show which labels are left out of the plan and why
+	o.displaySkippedLabels(result)
+
 	// Debug: Show first few labels for troubleshooting
-	fmt.Printf("\n🔍 Sample labels found:\n")
+	o.printf("\n🔍 Sample labels found:\n")
 	count := 0
 	for _, label := range result.PeriodLabels {
 		if count < 5 {
-			fmt.Printf("   - %s (ID: %s)\n", label.Name, label.Id)
+			o.printf("   - %s (ID: %s)\n", label.Name, label.Id)
 			count++
 		}
 	}
 	if len(result.PeriodLabels) > 5 {
-		fmt.Printf("   ... and %d more\n", len(result.PeriodLabels)-5)
+		o.printf("   ... and %d more\n", len(result.PeriodLabels)-5)
 	}
-	fmt.Println()
+	o.println()
 
 	// Check for conflicts
-	conflicts := o.analyzer.CheckConflicts(result.Transformations)
+	conflicts := o.analyzer.CheckConflicts(result.Transformations, result.ExistingLabels)
 	if len(conflicts) > 0 {
-		fmt.Println("⚠️  CONFLICTS DETECTED:")
+		o.println("⚠️  CONFLICTS DETECTED:")
 		for _, conflict := range conflicts {
-			fmt.Printf("   - %s\n", conflict)
+			o.printf("   - %s\n", conflict)
 		}
-		fmt.Println()
+		o.println()
 	}
 
+	// Gmail treats names case-insensitively, so these renames may fail despite no exact match
+	if warnings := o.analyzer.FindNearDuplicates(result.Transformations, result.ExistingLabels); len(warnings) > 0 {
+		o.println("⚠️  Case-only near-duplicates (Gmail may reject these renames):")
+		for _, warning := range warnings {
+			o.printf("   - %s\n", warning)
+		}
+		o.println()
+	}
+	if ambiguous := analyzer.FindAmbiguousLabels(result.ExistingLabels); len(ambiguous) > 0 {
+		o.printAmbiguous(ambiguous)
+		o.println()
+	}
+	o.printDependencyWarnings(result)
+	o.printPaddedSegments(result)
+
 	// Display transformations table
-	o.displayTransformationsTable(result.Transformations)
+	shown := result.Transformations
+	if opts.OnlyConflicts {
+		var hidden int
+		shown, hidden = o.conflictingOnly(result)
+		o.printf("🙈 Showing %d transformations with conflicts, hiding %d without\n", len(shown), hidden)
+	}
+	results := opts.results()
+	if o.config.Plain {
+		results = NewPlainWriter(results)
+	}
+	if opts.Output == OutputTree {
+		displayTree(results, shown)
+	} else if opts.GroupByRoot {
+		o.displayGroupedTables(results, shown, opts.Sort)
+	} else {
+		o.displayTransformationsTable(results, shown, opts.Sort)
+	}
 
-	fmt.Printf("\n💡 Next steps:\n")
-	fmt.Printf("   - Fix specific label: gmail-label-fixer fix --label \"LabelName\"\n")
-	fmt.Printf("   - Fix all labels: gmail-label-fixer fix --all\n")
+	// Show which parent labels Gmail will create on its own
+	o.displayNewParents(result)
+	if len(result.NewParents) > 0 {
+		o.printf("📈 Your label count will increase by %d (new parent labels).\n", len(result.NewParents))
+	}
+
+	o.printf("\n💡 Next steps:\n")
+	o.printf("   - Fix specific label: gmail-label-fixer fix --label \"LabelName\" --commit\n")
+	o.printf("   - Fix all labels: gmail-label-fixer fix --all --commit\n")
 
 	return nil
 }
 
-func (o *Operations) displayTransformationsTable(transformations map[string]*analyzer.LabelTransformation) {
-	table := tablewriter.NewTable(os.Stdout,
-		tablewriter.WithHeader([]string{"Current Label", "New Nested Structure", "Messages"}),
-	)
-
-	// Sort labels for consistent output
+// displayNewParents lists, per transformation, the parent paths that don't exist yet and will be auto-created
+func (o *Operations) displayNewParents(result *analyzer.AnalysisResult) {
 	var labels []string
-	for label := range transformations {
+	for label := range result.Transformations {
 		labels = append(labels, label)
 	}
 	sort.Strings(labels)
 
+	newParents := make(map[string]bool)
+	var lines []string
 	for _, label := range labels {
-		transformation := transformations[label]
+		missing := analyzer.MissingParents(result.Transformations[label], result.ExistingLabels, result.Transformations)
+		if len(missing) == 0 {
+			continue
+		}
+		for _, parent := range missing {
+			newParents[parent] = true
+		}
+		lines = append(lines, fmt.Sprintf("   - %s → creates %s", label, strings.Join(missing, ", ")))
+	}
+
+	if len(newParents) == 0 {
+		o.println("\n📁 No new parent labels will be created")
+		return
+	}
 
+	o.printf("\n📁 New parent labels Gmail will create (%d):\n", len(newParents))
+	for _, line := range lines {
+		o.println(line)
+	}
+}
+
+// countFailures lists the labels whose message count failed during analysis, sorted by name
+func countFailures(result *analyzer.AnalysisResult) []string {
+	var failures []string
+	for name, err := range result.CountErrors {
+		failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+// displaySkippedLabels lists labels excluded from the plan along with the reason for each
+func (o *Operations) displaySkippedLabels(result *analyzer.AnalysisResult) {
+	var skipped []string
+	byPrefix := 0
+	for _, skippedLabel := range result.SkippedLabels {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", skippedLabel.Label.Name, skippedLabel.Reason))
+		if skippedLabel.Reason == gmail.SkipReasonPrefix {
+			byPrefix++
+		}
+	}
+	for _, transformation := range result.Transformations {
+		if problems := analyzer.ValidateTransformation(transformation); len(problems) > 0 {
+			skipped = append(skipped, fmt.Sprintf("%s (validation failure: %s)", transformation.OriginalLabel, strings.Join(problems, "; ")))
+		}
+	}
+	if len(skipped) == 0 {
+		return
+	}
+	sort.Strings(skipped)
+
+	o.printf("\n⏭️  Skipped labels (%d):\n", len(skipped))
+	for _, label := range skipped {
+		o.printf("   - %s\n", label)
+	}
+	if byPrefix > 0 {
+		o.printf("   %d of them skipped by --skip-prefix\n", byPrefix)
+	}
+}
+
+// printPaddedSegments lists labels with stray spaces around their segments: as trimmed with
+// --trim-segments, and as a warning otherwise
+func (o *Operations) printPaddedSegments(result *analyzer.AnalysisResult) {
+	if o.config.ParseOptions.Reverse {
+		return // --trim-segments only applies when converting to nested names
+	}
+	var padded []string
+	for label := range result.Transformations {
+		if analyzer.HasPaddedSegments(label, ".") {
+			padded = append(padded, label)
+		}
+	}
+	if len(padded) == 0 {
+		return
+	}
+	sort.Strings(padded)
+
+	if o.config.ParseOptions.TrimSegments {
+		o.printf("✂️  Trimmed stray spaces from the segments of %d labels:\n", len(padded))
+		for _, label := range padded {
+			o.printf("   - '%s' → '%s'\n", label, result.Transformations[label].NestedStructure)
+		}
+	} else {
+		o.printf("⚠️  %d labels have segments with stray spaces (use --trim-segments to remove them):\n", len(padded))
+		for _, label := range padded {
+			o.printf("   - '%s'\n", label)
+		}
+	}
+	o.println()
+}
+
+func (o *Operations) displayTransformationsTable(w io.Writer, transformations map[string]*analyzer.LabelTransformation, order SortOrder) {
+	table := tablewriter.NewTable(w,
+		tablewriter.WithHeader([]string{"Current Label", "New Nested Structure", "Messages"}),
+	)
+
+	// Sort labels for consistent output
+	for _, transformation := range sortTransformations(transformations, order) {
 		table.Append([]string{
 			transformation.OriginalLabel,
 			transformation.NestedStructure,
@@ -209,96 +637,286 @@ func (o *Operations) displayTransformationsTable(transformations map[string]*ana
 	table.Render()
 }
 
-func (o *Operations) FixLabel(labelName string) error {
-	fmt.Printf("🔧 Fixing label: %s\n", labelName)
+// printDependencyWarnings lists parents this run creates that a skipped label would later need as its own name
+func (o *Operations) printDependencyWarnings(result *analyzer.AnalysisResult) {
+	warnings := o.analyzer.FindDependencyWarnings(result.Transformations, result.SkippedLabels)
+	if len(warnings) == 0 {
+		return
+	}
+	o.println("⚠️  Dependency warnings:")
+	for _, warning := range warnings {
+		o.printf("   - %s\n", warning)
+	}
+	o.println()
+}
+
+// displayGroupedTables renders a separate table for each top-level segment of the nested names
+func (o *Operations) displayGroupedTables(w io.Writer, transformations map[string]*analyzer.LabelTransformation, order SortOrder) {
+	groups := make(map[string]map[string]*analyzer.LabelTransformation)
+	for label, transformation := range transformations {
+		root := ""
+		if len(transformation.HierarchyParts) > 0 {
+			root = transformation.HierarchyParts[0]
+		}
+		if groups[root] == nil {
+			groups[root] = make(map[string]*analyzer.LabelTransformation)
+		}
+		groups[root][label] = transformation
+	}
+
+	var roots []string
+	for root := range groups {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		messages := 0
+		for _, transformation := range groups[root] {
+			messages += transformation.MessageCount
+		}
+		fmt.Fprintf(w, "\n📂 %s (%d labels, %d messages)\n", root, len(groups[root]), messages)
+		o.displayTransformationsTable(w, groups[root], order)
+	}
+}
+
+// FixLabel fixes a label and all its children
+func (o *Operations) FixLabel(ctx context.Context, labelName string) (*Result, error) {
+	o.printf("🔧 Fixing label: %s\n", labelName)
 
 	// Find the specific label and all its children
-	transformations, err := o.findLabelWithChildren(labelName)
+	transformations, err := o.findLabelWithChildren(ctx, labelName)
 	if err != nil {
-		return err
+		if errors.Is(err, ErrNothingToProcess) && o.alreadyConverted(ctx, labelName) {
+			return &Result{}, nil
+		}
+		return nil, err
+	}
+
+	return o.fixSubtree(ctx, transformations)
+}
+
+// FixPrefix fixes every period-separated label whose name starts with prefix, e.g. "Work."
+func (o *Operations) FixPrefix(ctx context.Context, prefix string) (*Result, error) {
+	separator := o.config.ParseOptions.SourceSeparator()
+	if !strings.HasSuffix(prefix, separator) {
+		prefix += separator // "Work" should not pick up "Workshop.2024"
 	}
+	o.printf("🔧 Fixing labels under prefix: %s\n", prefix)
 
-	if len(transformations) == 1 {
+	transformations, err := o.findLabelsMatching(ctx, func(name string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(transformations) == 0 {
+		return nil, fmt.Errorf("no period-separated labels start with '%s': %w", prefix, ErrNothingToProcess)
+	}
+	o.printf("   %d labels match prefix %s\n", len(transformations), prefix)
+
+	return o.fixSubtree(ctx, transformations)
+}
+
+// fixSubtree applies the resume and size filters to a scoped set of transformations and processes them
+func (o *Operations) fixSubtree(ctx context.Context, transformations []*analyzer.LabelTransformation) (*Result, error) {
+	if err := o.checkStep(); err != nil {
+		return nil, err
+	}
+	if err := o.checkAmbiguousLabels(ctx); err != nil {
+		return nil, err
+	}
+	byLabel := make(map[string]*analyzer.LabelTransformation, len(transformations))
+	for _, transformation := range transformations {
+		byLabel[transformation.OriginalLabel] = transformation
+	}
+	if err := o.checkFlattenCollisions(byLabel); err != nil {
+		return nil, err
+	}
+
+	found := len(transformations)
+	if o.config.Resume {
+		labels, err := o.existingLabels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if transformations, err = o.skipResumed(transformations, labels); err != nil {
+			return nil, err
+		}
+		if len(transformations) == 0 {
+			o.println("✅ Nothing left to resume, all labels were already renamed!")
+			return &Result{}, nil
+		}
+	}
+
+	transformations, tooSmall := o.dropSmallLabels(transformations)
+	if len(transformations) == 0 {
+		o.println("✅ Nothing to fix after skipping small labels")
+		return nil, ErrNothingToProcess
+	}
+	skipped := found - len(transformations)
+
+	if len(transformations) == 1 && len(tooSmall) == 0 {
 		// Single label
 		transformation := transformations[0]
-		fmt.Printf("   %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
-		return o.processTransformation(transformation)
+		if o.step == nil || o.config.Preview {
+			o.printf("   %s\n", o.describePlan(transformation))
+		}
+		if o.previewOnly() {
+			return &Result{Skipped: found}, nil
+		}
+		if o.step != nil && o.approveStep(transformation) != stepApply {
+			o.println("🛑 Skipped. No labels were changed.")
+			return &Result{Skipped: skipped + 1}, nil
+		}
+		result := &batchResult{total: 1, started: 1, skipped: skipped}
+		var err error
+		if o.shouldDelete(transformation) {
+			if err = o.deleteEmptyLabel(ctx, transformation); err == nil {
+				result.succeedDelete(transformation)
+			}
+		} else {
+			if err = o.processTransformation(ctx, transformation); err == nil {
+				result.succeed(transformation)
+			}
+		}
+		if err != nil {
+			result.fail(transformation.OriginalLabel, err)
+		}
+		o.analyzer.ForgetCount(transformation.OriginalID)
+		o.printCopied()
+		o.printQuotaUsed()
+		o.writeReport(ctx, result)
+		if err != nil {
+			return result.result(), fmt.Errorf("%w: %w", ErrLabelsFailed, err)
+		}
+		return result.result(), nil
 	} else {
-		// Parent label with children
-		fmt.Printf("   Found %d labels (parent + %d children) to fix:\n", len(transformations), len(transformations)-1)
+		// Parent label with children, or a whole prefix
+		o.printf("   Found %d labels to fix:\n", len(transformations))
 		for i, transformation := range transformations {
-			fmt.Printf("   [%d/%d] %s → %s\n", i+1, len(transformations), transformation.OriginalLabel, transformation.NestedStructure)
+			o.printf("   [%d/%d] %s\n", i+1, len(transformations), o.describePlan(transformation))
+		}
+		if o.previewOnly() {
+			return &Result{Skipped: found}, nil
 		}
 
 		// Process all transformations
-		processed := 0
-		for i, transformation := range transformations {
-			fmt.Printf("\n[%d/%d] Processing: %s\n", i+1, len(transformations), transformation.OriginalLabel)
+		result := o.processTransformations(ctx, transformations)
+		result.tooSmall = len(tooSmall)
+		result.skipped += skipped
+		o.printBatchSummary(ctx, result)
+		o.writeReport(ctx, result)
+		return result.result(), result.err(ctx)
+	}
+}
 
-			if err := o.processTransformation(transformation); err != nil {
-				fmt.Printf("❌ Failed: %v\n", err)
-				continue
-			}
+// belowConfirmThreshold reports whether a run is small enough, in labels and in messages, to
+// skip the confirmation prompt
+func (o *Operations) belowConfirmThreshold(transformations []*analyzer.LabelTransformation) bool {
+	if o.config.ConfirmThreshold <= 0 || len(transformations) > o.config.ConfirmThreshold {
+		return false
+	}
+	messages := 0
+	for _, transformation := range transformations {
+		messages += transformation.MessageCount
+	}
+	return messages <= o.config.ConfirmThreshold
+}
 
-			processed++
-			fmt.Printf("✅ Success: %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
-		}
+// previewOnly prints the preview banner and reports whether the run must stop before changing
+// anything, i.e. fix ran without --commit
+func (o *Operations) previewOnly() bool {
+	if !o.config.Preview {
+		return false
+	}
+	o.println("\n👀 PREVIEW MODE — no changes made. Re-run with --commit to apply them.")
+	return true
+}
 
-		fmt.Printf("\n🎉 Completed! Processed %d/%d labels successfully.\n", processed, len(transformations))
-		return nil
+// alreadyConverted reports, and prints, whether a label missing as a period-separated label
+// exists under its nested name, i.e. an earlier run already converted it
+func (o *Operations) alreadyConverted(ctx context.Context, labelName string) bool {
+	transformation := o.analyzer.Parse(labelName)
+	if transformation == nil || transformation.NestedStructure == "" {
+		return false
 	}
+	if _, exists, err := o.findLabel(ctx, transformation.NestedStructure); err != nil || !exists {
+		return false
+	}
+	o.printf("✅ %s was already converted to %s, nothing to do\n", labelName, transformation.NestedStructure)
+	return true
 }
 
 // findLabelWithChildren finds a label and all its children for hierarchical processing
-func (o *Operations) findLabelWithChildren(labelName string) ([]*analyzer.LabelTransformation, error) {
+func (o *Operations) findLabelWithChildren(ctx context.Context, labelName string) ([]*analyzer.LabelTransformation, error) {
+	if isGlob(labelName) {
+		return o.findLabelsByGlob(ctx, labelName)
+	}
+
+	labelPrefix := labelName + o.config.ParseOptions.SourceSeparator()
+
+	// Find the target label and all its children
+	transformations, err := o.findLabelsMatching(ctx, func(name string) bool {
+		return name == labelName || strings.HasPrefix(name, labelPrefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(transformations) == 0 {
+		return nil, fmt.Errorf("label '%s' not found or is not period-separated: %w", labelName, ErrNothingToProcess)
+	}
+	return transformations, nil
+}
+
+// findLabelsMatching builds transformations for the processable labels whose names satisfy match,
+// parents before children
+func (o *Operations) findLabelsMatching(ctx context.Context, match func(name string) bool) ([]*analyzer.LabelTransformation, error) {
 	// Get all period-separated labels
-	periodLabels, err := o.client.FindPeriodSeparatedLabels()
+	periodLabels, err := o.client.FindPeriodSeparatedLabels(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find labels: %v", err)
 	}
 
 	var matchingLabels []*gmailAPI.Label
-	labelPrefix := labelName + "."
-
-	// Find the target label and all its children
 	for _, label := range periodLabels {
-		if label.Name == labelName {
-			// Exact match - this is our target label
-			matchingLabels = append(matchingLabels, label)
-		} else if strings.HasPrefix(label.Name, labelPrefix) {
-			// This is a child label
+		if match(label.Name) {
 			matchingLabels = append(matchingLabels, label)
 		}
 	}
-
-	if len(matchingLabels) == 0 {
-		return nil, fmt.Errorf("label '%s' not found or is not period-separated", labelName)
+	matchingLabels, inactive, err := o.analyzer.FilterActive(ctx, matchingLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check label activity: %v", err)
+	}
+	for _, skipped := range inactive {
+		o.printf("   ⏭️  Skipping %s (%s)\n", skipped.Label.Name, skipped.Reason)
 	}
 
 	// Sort labels to process parents before children (shorter names first)
+	separator := o.config.ParseOptions.SourceSeparator()
 	sort.Slice(matchingLabels, func(i, j int) bool {
-		return len(strings.Split(matchingLabels[i].Name, ".")) < len(strings.Split(matchingLabels[j].Name, "."))
+		return len(strings.Split(matchingLabels[i].Name, separator)) < len(strings.Split(matchingLabels[j].Name, separator))
 	})
 
 	var transformations []*analyzer.LabelTransformation
 
 	// Create transformations for all matching labels
 	for _, label := range matchingLabels {
-		transformation := analyzer.ParseLabelHierarchy(label.Name)
+		transformation := o.analyzer.Parse(label.Name)
 		if transformation == nil {
-			fmt.Printf("   ⚠️  Skipping invalid label format: %s\n", label.Name)
+			o.printf("   ⚠️  Skipping invalid label format: %s\n", label.Name)
 			continue // Skip invalid labels
 		}
 
 		transformation.OriginalID = label.Id
 
 		// Get message count with proper error logging
-		messageIDs, err := o.client.GetMessagesWithLabel(label.Id)
+		messageCount, err := o.analyzer.MessageCount(ctx, label.Id)
 		if err != nil {
-			fmt.Printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", label.Name, err)
+			o.printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", label.Name, err)
 			transformation.MessageCount = 0 // Continue anyway
 		} else {
-			transformation.MessageCount = len(messageIDs)
+			transformation.MessageCount = messageCount
 		}
 
 		transformations = append(transformations, transformation)
@@ -308,9 +926,9 @@ func (o *Operations) findLabelWithChildren(labelName string) ([]*analyzer.LabelT
 }
 
 // findSpecificLabel finds and analyzes a single label without verbose output
-func (o *Operations) findSpecificLabel(labelName string) (*analyzer.LabelTransformation, error) {
+func (o *Operations) findSpecificLabel(ctx context.Context, labelName string) (*analyzer.LabelTransformation, error) {
 	// Get all period-separated labels
-	periodLabels, err := o.client.FindPeriodSeparatedLabels()
+	periodLabels, err := o.client.FindPeriodSeparatedLabels(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find labels: %v", err)
 	}
@@ -329,7 +947,7 @@ func (o *Operations) findSpecificLabel(labelName string) (*analyzer.LabelTransfo
 	}
 
 	// Create transformation for this specific label
-	transformation := analyzer.ParseLabelHierarchy(targetLabel.Name)
+	transformation := o.analyzer.Parse(targetLabel.Name)
 	if transformation == nil {
 		return nil, fmt.Errorf("label '%s' is not period-separated", labelName)
 	}
@@ -337,72 +955,186 @@ func (o *Operations) findSpecificLabel(labelName string) (*analyzer.LabelTransfo
 	transformation.OriginalID = targetLabel.Id
 
 	// Get message count with proper error handling
-	messageIDs, err := o.client.GetMessagesWithLabel(targetLabel.Id)
+	messageCount, err := o.analyzer.MessageCount(ctx, targetLabel.Id)
 	if err != nil {
-		fmt.Printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", targetLabel.Name, err)
+		o.printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", targetLabel.Name, err)
 		transformation.MessageCount = 0 // Continue anyway
 	} else {
-		transformation.MessageCount = len(messageIDs)
+		transformation.MessageCount = messageCount
 	}
 
 	return transformation, nil
 }
 
-func (o *Operations) FixAllLabels() error {
-	fmt.Println("🔧 Fixing all period-separated labels...")
+// checkFlattenCollisions refuses to run when --flatten-top maps different labels onto one name,
+// unless merging was asked for
+func (o *Operations) checkFlattenCollisions(transformations map[string]*analyzer.LabelTransformation) error {
+	if o.config.ParseOptions.FlattenTop <= 0 || o.config.OnConflict == OnConflictMerge {
+		return nil
+	}
+	collisions := analyzer.FindCollisions(transformations)
+	if len(collisions) == 0 {
+		return nil
+	}
+	o.printf("❌ --flatten-top %d makes labels collide:\n", o.config.ParseOptions.FlattenTop)
+	for _, collision := range collisions {
+		o.printf("   - %s\n", collision)
+	}
+	return fmt.Errorf("%d flattened names collide (use --on-conflict merge to combine them)", len(collisions))
+}
 
-	result, err := o.analyzer.AnalyzeLabels()
+func (o *Operations) FixAllLabels(ctx context.Context) (*Result, error) {
+	o.println("🔧 Fixing all period-separated labels...")
+
+	result, err := o.analyzer.AnalyzeLabels(ctx)
 	if err != nil {
-		return fmt.Errorf("analysis failed: %v", err)
+		return nil, fmt.Errorf("analysis failed: %v", err)
+	}
+
+	for _, skipped := range result.SkippedLabels {
+		if skipped.Reason == gmail.SkipReasonExcluded {
+			o.printf("⏭️  Skipping %s (%s)\n", skipped.Label.Name, skipped.Reason)
+		}
 	}
 
 	if len(result.Transformations) == 0 {
-		fmt.Println("✅ No period-separated labels found!")
-		return nil
+		o.println("✅ No period-separated labels found!")
+		return nil, ErrNothingToProcess
+	}
+	if err := o.checkStep(); err != nil {
+		return nil, err
+	}
+	if err := o.checkAmbiguousNames(result.ExistingLabels); err != nil {
+		return nil, err
+	}
+	o.printDependencyWarnings(result)
+	if err := o.checkFlattenCollisions(result.Transformations); err != nil {
+		return nil, err
 	}
 
-	// Process all transformations - Gmail will automatically create parent hierarchy when renaming
-	processed := 0
-	for _, transformation := range result.Transformations {
-		fmt.Printf("\n[%d/%d] Processing: %s\n", processed+1, len(result.Transformations), transformation.OriginalLabel)
+	var labels []string
+	for label := range result.Transformations {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
 
-		if err := o.processTransformation(transformation); err != nil {
-			fmt.Printf("❌ Failed: %v\n", err)
-			continue
+	var transformations []*analyzer.LabelTransformation
+	for _, label := range labels {
+		transformations = append(transformations, result.Transformations[label])
+	}
+
+	found := len(transformations)
+	if o.config.Resume {
+		if transformations, err = o.skipResumed(transformations, result.ExistingLabels); err != nil {
+			return nil, err
+		}
+		if len(transformations) == 0 {
+			o.println("✅ Nothing left to resume, all labels were already renamed!")
+			return &Result{}, nil
 		}
+	}
 
-		processed++
-		fmt.Printf("✅ Success: %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
+	transformations, tooSmall := o.dropSmallLabels(transformations)
+	if len(transformations) == 0 {
+		o.println("✅ Nothing to fix after skipping small labels")
+		return nil, ErrNothingToProcess
 	}
 
-	fmt.Printf("\n🎉 Completed! Processed %d/%d labels successfully.\n", processed, len(result.Transformations))
-	return nil
+	// Show the plan and let the user bail out before anything changes
+	o.printf("\n📋 %d labels will be changed:\n", len(transformations))
+	for _, transformation := range transformations {
+		o.printf("   %s\n", o.describePlan(transformation))
+	}
+	o.println()
+
+	if o.previewOnly() {
+		return &Result{Skipped: len(result.SkippedLabels) + found}, nil
+	}
+	if o.belowConfirmThreshold(transformations) {
+		o.printf("   %d labels are within --confirm-threshold %d, proceeding without asking\n", len(transformations), o.config.ConfirmThreshold)
+	} else if !o.confirm(fmt.Sprintf("Proceed with %d changes?", len(transformations))) {
+		o.println("🛑 Aborted. No labels were changed.")
+		return &Result{Skipped: len(result.SkippedLabels) + found}, nil
+	}
+
+	// Process all transformations - Gmail will automatically create parent hierarchy when renaming
+	batch := o.processTransformations(ctx, transformations)
+	batch.tooSmall = len(tooSmall)
+	batch.skipped += len(result.SkippedLabels) + found - len(transformations)
+	o.printBatchSummary(ctx, batch)
+	o.writeReport(ctx, batch)
+	return batch.result(), batch.err(ctx)
 }
 
-func (o *Operations) processTransformation(transformation *analyzer.LabelTransformation) error {
+func (o *Operations) processTransformation(ctx context.Context, transformation *analyzer.LabelTransformation) error {
+	// Let an interrupted run finish the label in progress; only the deadline still applies
+	ctx, cancel := withoutCancel(ctx)
+	defer cancel()
+
+	// Reject names Gmail would refuse before making any API calls
+	if problems := analyzer.ValidateTransformation(transformation); len(problems) > 0 {
+		return fmt.Errorf("invalid nested name '%s': %s", transformation.NestedStructure, strings.Join(problems, "; "))
+	}
+
 	// Check if target label name already exists
-	if existingLabel, exists := o.client.LabelExists(transformation.NestedStructure); exists {
-		return fmt.Errorf("target label '%s' already exists (ID: %s). Cannot rename to existing label", transformation.NestedStructure, existingLabel.Id)
+	existingLabel, exists, err := o.findLabel(ctx, transformation.NestedStructure)
+	if err != nil {
+		return err
+	}
+	if exists {
+		merge := o.config.OnConflict == OnConflictMerge
+		if !merge && o.createdThisRun(existingLabel.Name) {
+			// This run created the label as a parent of an earlier rename, so it holds nothing
+			// of the user's and the source's messages can move into it
+			o.detailf("   🧱 %s was created as a parent earlier in this run, moving messages into it\n", existingLabel.Name)
+			merge = true
+		}
+		if merge && o.config.PreserveOriginal {
+			return o.copyMessages(ctx, transformation, existingLabel, false)
+		}
+		if merge {
+			return o.mergeIntoExisting(ctx, transformation, existingLabel)
+		}
+		if analyzer.StripsInboxPrefix(transformation) {
+			return errors.New(analyzer.InboxStripConflict(transformation, existingLabel))
+		}
+		return fmt.Errorf("target label '%s' already exists (ID: %s). Cannot rename to existing label (use --on-conflict merge to move its messages)", transformation.NestedStructure, existingLabel.Id)
+	}
+
+	if o.config.HiddenParents {
+		if err := o.createHiddenParents(ctx, transformation); err != nil {
+			return err
+		}
+	}
+
+	if o.config.PreserveOriginal {
+		return o.copyToNewLabel(ctx, transformation)
 	}
 
 	// Simply rename the label - Gmail automatically preserves all message associations!
-	fmt.Printf("   Renaming label: %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
+	o.detailf("   Renaming label: %s → %s\n", transformation.OriginalLabel, transformation.NestedStructure)
 
 	var renamedLabel *gmailAPI.Label
-	err := o.retryWithBackoff(func() error {
+	err = o.retryWithBackoff(ctx, func() error {
 		var err error
-		renamedLabel, err = o.client.RenameLabel(transformation.OriginalID, transformation.NestedStructure)
+		renamedLabel, err = o.client.RenameLabelIfMatches(ctx, transformation.OriginalID, transformation.OriginalLabel, transformation.NestedStructure)
 		return err
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to rename label: %v", err)
+		o.logger().Error("label rename failed", "label_id", transformation.OriginalID, "from", transformation.OriginalLabel, "to", transformation.NestedStructure, "error", err)
+		return fmt.Errorf("failed to rename label: %w", err)
 	}
 
-	o.withRateLimit()
+	o.withRateLimit(ctx)
+
+	o.logger().Info("label renamed", "label_id", renamedLabel.Id, "from", transformation.OriginalLabel, "to", renamedLabel.Name, "message_count", transformation.MessageCount)
+
+	o.labelRenamed(transformation.OriginalLabel, renamedLabel)
+	o.recordRename(transformation.OriginalID, transformation.OriginalLabel, renamedLabel.Name)
 
-	fmt.Printf("   ✅ Successfully renamed to: %s (ID: %s)\n", renamedLabel.Name, renamedLabel.Id)
-	fmt.Printf("   📧 All %d messages automatically preserved\n", transformation.MessageCount)
+	o.detailf("   ✅ Successfully renamed to: %s (ID: %s)\n", renamedLabel.Name, renamedLabel.Id)
+	o.detailf("   📧 All %d messages automatically preserved\n", transformation.MessageCount)
 
 	return nil
 }
@@ -0,0 +1,176 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// newTestOperations runs operations against fake without prompts, delays or journaling
+func newTestOperations(fake *fakeService, configure ...func(*Config)) *Operations {
+	config := &Config{
+		MaxRetries:  defaultMaxRetries,
+		Output:      io.Discard,
+		AssumeYes:   true,
+		Concurrency: 1,
+		OnConflict:  OnConflictFail,
+	}
+	for _, apply := range configure {
+		apply(config)
+	}
+	return NewOperationsWithConfig(fake, config)
+}
+
+// labelCounts maps every label name in fake to its message count
+func labelCounts(t *testing.T, fake *fakeService) map[string]int {
+	t.Helper()
+	labels, err := fake.GetAllLabels(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllLabels: %v", err)
+	}
+	counts := make(map[string]int, len(labels))
+	for _, label := range labels {
+		count, err := fake.GetLabelMessageCount(context.Background(), label.Id)
+		if err != nil {
+			t.Fatalf("GetLabelMessageCount(%s): %v", label.Name, err)
+		}
+		counts[label.Name] = count
+	}
+	return counts
+}
+
+// retryNowError is a transient Gmail error that asks to be retried right away
+func retryNowError(code int) error {
+	header := http.Header{}
+	header.Set("Retry-After", "0")
+	return &googleapi.Error{Code: code, Header: header}
+}
+
+func TestFixAllLabelsRenamesPeriodLabels(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "work", Name: "Work.Projects"},
+		{Id: "x", Name: "Work.Projects.X"},
+		{Id: "home", Name: "Home"},
+	})
+	fake.AddMessage("m1", "work")
+	fake.AddMessage("m2", "work", "x")
+	fake.AddMessage("m3", "home")
+
+	result, err := newTestOperations(fake).FixAllLabels(context.Background())
+	if err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+	if result.Succeeded != 2 || len(result.Failed) != 0 {
+		t.Errorf("got %d succeeded and %d failed, want 2 and 0", result.Succeeded, len(result.Failed))
+	}
+
+	counts := labelCounts(t, fake)
+	want := map[string]int{"Work": 0, "Work/Projects": 2, "Work/Projects/X": 1, "Home": 1}
+	if len(counts) != len(want) {
+		t.Errorf("labels after fix = %v, want %v", counts, want)
+	}
+	for name, count := range want {
+		if got, ok := counts[name]; !ok || got != count {
+			t.Errorf("label %s has %d messages (exists: %v), want %d", name, got, ok, count)
+		}
+	}
+}
+
+func TestFixAllLabelsFailsOnExistingTarget(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "old", Name: "Work.Acme"},
+		{Id: "new", Name: "Work/Acme"},
+	})
+	fake.AddMessage("m1", "old")
+	fake.AddMessage("m2", "new")
+
+	result, err := newTestOperations(fake).FixAllLabels(context.Background())
+	if !errors.Is(err, ErrLabelsFailed) {
+		t.Fatalf("FixAllLabels error = %v, want ErrLabelsFailed", err)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Label != "Work.Acme" {
+		t.Errorf("failed labels = %v, want Work.Acme", result.Failed)
+	}
+
+	counts := labelCounts(t, fake)
+	if counts["Work.Acme"] != 1 || counts["Work/Acme"] != 1 {
+		t.Errorf("labels changed despite the conflict: %v", counts)
+	}
+}
+
+func TestFixAllLabelsMergesIntoExistingTarget(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "old", Name: "Work.Acme"},
+		{Id: "new", Name: "Work/Acme"},
+	})
+	fake.AddMessage("m1", "old")
+	fake.AddMessage("m2", "old")
+	fake.AddMessage("m3", "new")
+
+	ops := newTestOperations(fake, func(config *Config) { config.OnConflict = OnConflictMerge })
+	if _, err := ops.FixAllLabels(context.Background()); err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+
+	counts := labelCounts(t, fake)
+	if _, exists := counts["Work.Acme"]; exists {
+		t.Errorf("source label Work.Acme was not deleted")
+	}
+	if counts["Work/Acme"] != 3 {
+		t.Errorf("Work/Acme has %d messages, want 3", counts["Work/Acme"])
+	}
+}
+
+func TestFixAllLabelsRetriesTransientErrors(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{{Id: "old", Name: "Work.Acme"}})
+	fake.Errors["RenameLabel"] = []error{retryNowError(http.StatusServiceUnavailable)}
+
+	result, err := newTestOperations(fake).FixAllLabels(context.Background())
+	if err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+	if result.Succeeded != 1 {
+		t.Errorf("got %d succeeded, want 1", result.Succeeded)
+	}
+	if calls := fake.Calls["RenameLabel"]; calls != 2 {
+		t.Errorf("RenameLabel called %d times, want 2", calls)
+	}
+}
+
+func TestFixAllLabelsListsLabelsOncePerRun(t *testing.T) {
+	listCalls := func(n int) int {
+		var labels []*gmailAPI.Label
+		for i := 0; i < n; i++ {
+			labels = append(labels, &gmailAPI.Label{Id: fmt.Sprint("label", i), Name: fmt.Sprintf("Work.Client%d", i)})
+		}
+		fake := newFakeService(labels)
+		if _, err := newTestOperations(fake, func(config *Config) { config.HiddenParents = true }).FixAllLabels(context.Background()); err != nil {
+			t.Fatalf("FixAllLabels with %d labels: %v", n, err)
+		}
+		return fake.Calls["GetAllLabels"]
+	}
+
+	if few, many := listCalls(2), listCalls(20); few != many {
+		t.Errorf("GetAllLabels called %d times for 2 labels but %d times for 20", few, many)
+	}
+}
+
+func TestDryRunPlainGroupedTables(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{{Id: "work", Name: "Work.Projects"}})
+	var results strings.Builder
+
+	ops := newTestOperations(fake, func(config *Config) { config.Plain = true })
+	if err := ops.DryRun(context.Background(), DryRunOptions{Output: OutputTable, GroupByRoot: true, Results: &results}); err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if strings.Contains(results.String(), "📂") || !strings.Contains(results.String(), "[INFO] Work") {
+		t.Errorf("grouped tables not rendered plain:\n%s", results.String())
+	}
+}
@@ -0,0 +1,164 @@
+package operations
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table" // Human-readable table output (default)
+	OutputTree  = "tree"  // Human-readable indented tree of the new hierarchy
+	OutputJSON  = "json"  // Machine-readable JSON output
+	OutputYAML  = "yaml"  // Machine-readable YAML output, friendlier to review in diffs
+	OutputCSV   = "csv"   // One row per transformation, for opening the plan in a spreadsheet
+)
+
+// OutputFormats lists the supported values for the analyze --output flag
+var OutputFormats = []string{OutputTable, OutputTree, OutputJSON, OutputYAML, OutputCSV}
+
+// IsMachineReadable reports whether an output format is a document for scripts rather than people
+func IsMachineReadable(format string) bool {
+	return format == OutputJSON || format == OutputYAML || format == OutputCSV
+}
+
+type labelOutput struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+type transformationOutput struct {
+	OriginalLabel   string   `json:"originalLabel" yaml:"originalLabel"`
+	NestedStructure string   `json:"nestedStructure" yaml:"nestedStructure"`
+	MessageCount    int      `json:"messageCount" yaml:"messageCount"`
+	RequiredParents []string `json:"requiredParents" yaml:"requiredParents"`
+	Conflicts       []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+type analysisOutput struct {
+	PeriodLabels    []labelOutput          `json:"periodLabels" yaml:"periodLabels"`
+	Transformations []transformationOutput `json:"transformations" yaml:"transformations"`
+	Conflicts       []string               `json:"conflicts" yaml:"conflicts"`
+	Warnings        []string               `json:"warnings" yaml:"warnings"`
+	TotalMessages   int                    `json:"totalMessages" yaml:"totalMessages"`
+	NewParents      []string               `json:"newParents" yaml:"newParents"`
+	SampledFrom     int                    `json:"sampledFrom,omitempty" yaml:"sampledFrom,omitempty"`
+}
+
+// newAnalysisOutput converts an analysis result into its serializable form, sorted by label name
+func newAnalysisOutput(result *analyzer.AnalysisResult, conflicts, warnings []string) *analysisOutput {
+	out := &analysisOutput{
+		PeriodLabels:    []labelOutput{},
+		Transformations: []transformationOutput{},
+		Conflicts:       conflicts,
+		Warnings:        warnings,
+		TotalMessages:   result.TotalMessages,
+		NewParents:      result.NewParents,
+		SampledFrom:     result.SampledFrom,
+	}
+	if out.NewParents == nil {
+		out.NewParents = []string{}
+	}
+	if out.Conflicts == nil {
+		out.Conflicts = []string{}
+	}
+	if out.Warnings == nil {
+		out.Warnings = []string{}
+	}
+
+	for _, label := range result.PeriodLabels {
+		out.PeriodLabels = append(out.PeriodLabels, labelOutput{ID: label.Id, Name: label.Name})
+	}
+	sort.Slice(out.PeriodLabels, func(i, j int) bool {
+		return out.PeriodLabels[i].Name < out.PeriodLabels[j].Name
+	})
+
+	reasons := analyzer.ConflictReasons(result.Transformations, result.ExistingLabels)
+	var labels []string
+	for label := range result.Transformations {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		transformation := result.Transformations[label]
+		parents := transformation.RequiredParents
+		if parents == nil {
+			parents = []string{}
+		}
+		out.Transformations = append(out.Transformations, transformationOutput{
+			OriginalLabel:   transformation.OriginalLabel,
+			NestedStructure: transformation.NestedStructure,
+			MessageCount:    transformation.MessageCount,
+			RequiredParents: parents,
+			Conflicts:       reasons[label],
+		})
+	}
+
+	return out
+}
+
+// csvHeader lists the columns of the csv analyze output
+var csvHeader = []string{"original_label", "nested_structure", "message_count", "required_parents", "conflict", "conflict_reason"}
+
+// writeAnalysisCSV writes one row per transformation of the serialized analysis
+func writeAnalysisCSV(w io.Writer, out *analysisOutput) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+	for _, transformation := range out.Transformations {
+		conflict := "no"
+		if len(transformation.Conflicts) > 0 {
+			conflict = "yes"
+		}
+		row := []string{
+			transformation.OriginalLabel,
+			transformation.NestedStructure,
+			strconv.Itoa(transformation.MessageCount),
+			strings.Join(transformation.RequiredParents, ";"),
+			conflict,
+			strings.Join(transformation.Conflicts, "; "),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row for %s: %v", transformation.OriginalLabel, err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output: %v", err)
+	}
+	return nil
+}
+
+// writeAnalysis writes the analysis result to w as a single JSON or YAML document, or as CSV
+// rows. All formats share newAnalysisOutput, so they always carry the same data.
+func writeAnalysis(w io.Writer, format string, result *analyzer.AnalysisResult, conflicts, warnings []string) error {
+	out := newAnalysisOutput(result, conflicts, warnings)
+
+	switch format {
+	case OutputCSV:
+		return writeAnalysisCSV(w, out)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(out); err != nil {
+			return fmt.Errorf("failed to write YAML output: %v", err)
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			return fmt.Errorf("failed to write JSON output: %v", err)
+		}
+		return nil
+	}
+}
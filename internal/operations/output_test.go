@@ -0,0 +1,57 @@
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"gmail-label-fixer/internal/analyzer"
+	"reflect"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteAnalysisRoundTrips(t *testing.T) {
+	existing := map[string]*gmailAPI.Label{
+		"Work.Acme": {Id: "acme", Name: "Work.Acme"},
+		"Work/Beta": {Id: "beta-nested", Name: "Work/Beta"},
+		"Work.Beta": {Id: "beta", Name: "Work.Beta"},
+	}
+	transformations := analyzer.BuildHierarchyMap([]string{"Work.Acme", "Work.Beta"})
+	transformations["Work.Acme"].MessageCount = 4
+	result := &analyzer.AnalysisResult{
+		PeriodLabels:    []*gmailAPI.Label{existing["Work.Beta"], existing["Work.Acme"]},
+		Transformations: transformations,
+		ExistingLabels:  existing,
+		TotalMessages:   4,
+		NewParents:      []string{"Work"},
+	}
+	want := newAnalysisOutput(result, []string{"Work/Beta already exists"}, nil)
+
+	decoders := map[string]func([]byte, any) error{
+		OutputJSON: json.Unmarshal,
+		OutputYAML: yaml.Unmarshal,
+	}
+	for format, decode := range decoders {
+		t.Run(format, func(t *testing.T) {
+			var first, second bytes.Buffer
+			if err := writeAnalysis(&first, format, result, want.Conflicts, nil); err != nil {
+				t.Fatalf("writeAnalysis: %v", err)
+			}
+			if err := writeAnalysis(&second, format, result, want.Conflicts, nil); err != nil {
+				t.Fatalf("writeAnalysis: %v", err)
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Errorf("two runs wrote different documents:\n%s\n---\n%s", first.String(), second.String())
+			}
+
+			var got analysisOutput
+			if err := decode(first.Bytes(), &got); err != nil {
+				t.Fatalf("parsing the document back: %v\n%s", err, first.String())
+			}
+			if !reflect.DeepEqual(&got, want) {
+				t.Errorf("round trip = %+v, want %+v", got, *want)
+			}
+		})
+	}
+}
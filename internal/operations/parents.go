@@ -0,0 +1,44 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// createHiddenParents creates the transformation's missing parent labels with labelHide visibility
+// before the rename, so Gmail does not auto-create them shown in the sidebar
+func (o *Operations) createHiddenParents(ctx context.Context, transformation *analyzer.LabelTransformation) error {
+	// Serialize parent creation so concurrent workers don't create the same parent twice
+	o.parentsMu.Lock()
+	defer o.parentsMu.Unlock()
+
+	for _, parent := range transformation.RequiredParents {
+		_, exists, err := o.findLabel(ctx, parent)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		var created *gmailAPI.Label
+		err = o.retryWithBackoff(ctx, func() error {
+			var err error
+			created, err = o.client.CreateLabelWithVisibility(ctx, parent, "labelHide", "hide")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create hidden parent '%s': %w", parent, err)
+		}
+
+		o.withRateLimit(ctx)
+
+		o.labelCreated(created)
+		o.logger().Info("hidden parent created", "label_id", created.Id, "name", created.Name)
+		o.detailf("   🙈 Created hidden parent: %s\n", parent)
+	}
+	return nil
+}
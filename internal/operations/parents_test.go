@@ -0,0 +1,113 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gmail-label-fixer/internal/analyzer"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestFixAllLabelsKeepsMessagesOfLabelThatIsAlsoAParent(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "work", Name: "Work"},
+		{Id: "projects", Name: "Work.Projects"},
+		{Id: "x", Name: "Work.Projects.X"},
+	})
+	fake.AddMessage("m1", "work")
+	fake.AddMessage("m2", "projects")
+	fake.AddMessage("m3", "projects")
+	fake.AddMessage("m4", "x")
+
+	ops := newTestOperations(fake, func(config *Config) { config.Concurrency = 4 })
+	if _, err := ops.FixAllLabels(context.Background()); err != nil {
+		t.Fatalf("FixAllLabels: %v", err)
+	}
+
+	counts := labelCounts(t, fake)
+	want := map[string]int{"Work": 1, "Work/Projects": 2, "Work/Projects/X": 1}
+	if len(counts) != len(want) {
+		t.Errorf("labels after fix = %v, want %v", counts, want)
+	}
+	for name, count := range want {
+		if counts[name] != count {
+			t.Errorf("label %s has %d messages, want %d", name, counts[name], count)
+		}
+	}
+}
+
+func TestFixAllLabelsDoesNotMergeIntoPreexistingEmptyParent(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "old", Name: "Work.Projects"},
+		{Id: "parent", Name: "Work/Projects"},
+		{Id: "child", Name: "Work/Projects/X"},
+	})
+	fake.AddMessage("m1", "old")
+
+	_, err := newTestOperations(fake).FixAllLabels(context.Background())
+	if !errors.Is(err, ErrLabelsFailed) {
+		t.Fatalf("FixAllLabels error = %v, want ErrLabelsFailed", err)
+	}
+	if counts := labelCounts(t, fake); counts["Work.Projects"] != 1 || counts["Work/Projects"] != 0 {
+		t.Errorf("conflicting label was merged without --on-conflict merge: %v", counts)
+	}
+	if fake.Calls["DeleteLabel"] != 0 {
+		t.Errorf("DeleteLabel called %d times, want 0", fake.Calls["DeleteLabel"])
+	}
+}
+
+func TestMergeIntoParentCreatedThisRunIsJournaledAndUndone(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "child", Name: "A.B.C"},
+		{Id: "parent", Name: "A.B"},
+	})
+	fake.AddMessage("m1", "parent")
+	fake.AddMessage("m2", "parent")
+	fake.AddMessage("m3", "child")
+
+	journal := filepath.Join(t.TempDir(), "journal.json")
+	ops := newTestOperations(fake, func(config *Config) { config.JournalPath = journal })
+	ctx := context.Background()
+
+	// Renaming the child first makes Gmail create A/B, which the parent's rename then runs into
+	for _, name := range []string{"A.B.C", "A.B"} {
+		transformation := analyzer.ParseLabelHierarchy(name)
+		transformation.OriginalID = map[string]string{"A.B.C": "child", "A.B": "parent"}[name]
+		if err := ops.processTransformation(ctx, transformation); err != nil {
+			t.Fatalf("processTransformation(%s): %v", name, err)
+		}
+	}
+
+	counts := labelCounts(t, fake)
+	if _, exists := counts["A.B"]; exists || counts["A/B"] != 2 || counts["A/B/C"] != 1 {
+		t.Fatalf("labels after fix = %v, want A.B merged into A/B", counts)
+	}
+
+	if err := newTestOperations(fake, func(config *Config) { config.JournalPath = journal }).Undo(ctx); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	counts = labelCounts(t, fake)
+	if counts["A.B"] != 2 || counts["A/B"] != 0 || counts["A.B.C"] != 1 {
+		t.Errorf("labels after undo = %v, want A.B restored with its 2 messages", counts)
+	}
+}
+
+func TestRenameLabelNeverMerges(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "old", Name: "Old"},
+		{Id: "new", Name: "New"},
+		{Id: "child", Name: "New/Child"},
+	})
+	fake.AddMessage("m1", "old")
+
+	ops := newTestOperations(fake, func(config *Config) { config.OnConflict = OnConflictMerge })
+	if err := ops.RenameLabel(context.Background(), "Old", "New"); err == nil {
+		t.Fatal("RenameLabel into an existing label succeeded, want an error")
+	}
+	if counts := labelCounts(t, fake); counts["Old"] != 1 || counts["New"] != 0 {
+		t.Errorf("labels changed by a conflicting rename: %v", counts)
+	}
+}
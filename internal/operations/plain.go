@@ -0,0 +1,85 @@
+package operations
+
+import (
+	"io"
+	"strings"
+)
+
+// plainTags replaces each status emoji with an ASCII tag for --plain output. Narrow emoji are
+// followed by two spaces to line up with wide ones, which tags don't need, so those come first.
+var plainTags = strings.NewReplacer(
+	"⚠️  ", "[WARN] ",
+	"⏭️  ", "[SKIP] ",
+	"⏱️  ", "[TIME] ",
+	"✏️  ", "[FIX] ",
+	"✂️  ", "[FIX] ",
+	"🗑️  ", "[DEL] ",
+	"♻️  ", "[RESTORE] ",
+	"↩️  ", "[UNDO] ",
+	"↪️  ", "[RESUME] ",
+	"ℹ️  ", "[INFO] ",
+	"✅", "[OK]",
+	"🎉", "[OK]",
+	"⚠️", "[WARN]",
+	"💸", "[WARN]",
+	"🐢", "[WARN]",
+	"❌", "[ERR]",
+	"🛑", "[STOP]",
+	"⛔", "[STOP]",
+	"⏭️", "[SKIP]",
+	"🙈", "[SKIP]",
+	"⏳", "[WAIT]",
+	"⏰", "[WAIT]",
+	"⏱️", "[TIME]",
+	"🔍", "[SCAN]",
+	"🔎", "[SCAN]",
+	"🔬", "[SCAN]",
+	"🧹", "[SCAN]",
+	"🩺", "[CHECK]",
+	"🔧", "[FIX]",
+	"✏️", "[FIX]",
+	"✂️", "[FIX]",
+	"🔀", "[MERGE]",
+	"🧱", "[MERGE]",
+	"🗑️", "[DEL]",
+	"♻️", "[RESTORE]",
+	"↩️", "[UNDO]",
+	"↪️", "[RESUME]",
+	"💾", "[SAVE]",
+	"📤", "[SAVE]",
+	"📝", "[SAVE]",
+	"🔐", "[AUTH]",
+	"🌐", "[AUTH]",
+	"🔄", "[AUTH]",
+	"👤", "[INFO]",
+	"👀", "[INFO]",
+	"💡", "[INFO]",
+	"ℹ️", "[INFO]",
+	"📋", "[INFO]",
+	"📊", "[INFO]",
+	"📈", "[INFO]",
+	"📁", "[INFO]",
+	"📂", "[INFO]",
+	"📧", "[INFO]",
+)
+
+// plainWriter rewrites status emoji to ASCII tags before passing output on
+type plainWriter struct {
+	out io.Writer
+}
+
+// NewPlainWriter wraps w so every status emoji written to it becomes an ASCII tag
+// like [OK] or [WARN], for logs that do not render emoji
+func NewPlainWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*plainWriter); ok || w == io.Discard {
+		return w
+	}
+	return &plainWriter{out: w}
+}
+
+func (w *plainWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, plainTags.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,86 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth = 30 // Number of characters used for the bar itself
+)
+
+// progressBar renders a single-line progress indicator at the bottom of the terminal.
+// Messages printed while it is active are written above the bar.
+type progressBar struct {
+	mu      sync.Mutex
+	out     io.Writer
+	total   int
+	done    int
+	started time.Time
+}
+
+func newProgressBar(out io.Writer, total int) *progressBar {
+	bar := &progressBar{
+		out:     out,
+		total:   total,
+		started: time.Now(),
+	}
+	bar.mu.Lock()
+	bar.render()
+	bar.mu.Unlock()
+	return bar
+}
+
+// increment marks one more item as completed
+func (p *progressBar) increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.render()
+}
+
+// printAbove clears the bar, writes text, and redraws the bar underneath it
+func (p *progressBar) printAbove(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.out, "\r\033[K")
+	text = strings.TrimLeft(text, "\n")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	fmt.Fprint(p.out, text)
+	p.render()
+}
+
+// finish draws the final state and moves to a fresh line
+func (p *progressBar) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.render()
+	fmt.Fprintln(p.out)
+}
+
+// render draws the bar; the caller must hold p.mu
+func (p *progressBar) render() {
+	filled := 0
+	percent := 100
+	if p.total > 0 {
+		filled = progressBarWidth * p.done / p.total
+		percent = 100 * p.done / p.total
+	}
+
+	eta := "--"
+	if p.done > 0 && p.done < p.total {
+		perItem := time.Since(p.started) / time.Duration(p.done)
+		eta = (perItem * time.Duration(p.total-p.done)).Round(time.Second).String()
+	} else if p.done >= p.total {
+		eta = "0s"
+	}
+
+	fmt.Fprintf(p.out, "\r\033[K[%s%s] %d/%d (%d%%) ETA %s",
+		strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled),
+		p.done, p.total, percent, eta)
+}
@@ -0,0 +1,79 @@
+package operations
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// input returns the reader interactive answers are read from
+func (o *Operations) input() io.Reader {
+	if o.config.Input == nil {
+		return os.Stdin
+	}
+	return o.config.Input
+}
+
+// isTerminal reports whether the reader or writer is attached to a terminal
+func isTerminal(stream interface{}) bool {
+	file, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// isInteractive reports whether answers can be read from a terminal
+func (o *Operations) isInteractive() bool {
+	return isTerminal(o.input())
+}
+
+// confirm asks a yes/no question and returns true only for an explicit yes.
+// The prompt is skipped when AssumeYes is set or when input is not a terminal.
+func (o *Operations) confirm(question string) bool {
+	if o.config.AssumeYes {
+		return true
+	}
+	if !o.isInteractive() {
+		o.println("ℹ️  Input is not a terminal, skipping confirmation prompt")
+		return true
+	}
+
+	answer, ok := o.ask(question + " [y/N]: ")
+	if !ok {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ask prints a prompt and reads one line of answer, returning false when input is exhausted
+func (o *Operations) ask(prompt string) (string, bool) {
+	if o.quiet != nil {
+		// The question has to be seen even when status output is silenced
+		fmt.Fprint(os.Stderr, prompt)
+	} else {
+		o.printf("%s", prompt)
+	}
+
+	// Share one reader so buffered input is not lost between prompts
+	if o.answers == nil {
+		o.answers = bufio.NewReader(o.input())
+	}
+	answer, err := o.answers.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", false
+	}
+	return answer, true
+}
@@ -0,0 +1,43 @@
+package operations
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// quietWriter passes through only error lines, those starting with ❌, along with the indented
+// lines that follow them such as the list of failed labels. Everything else is dropped.
+type quietWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	inError bool
+}
+
+func (w *quietWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var kept strings.Builder
+	for _, line := range strings.SplitAfter(string(p), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			// Blank lines neither start nor end an error block
+		case strings.HasPrefix(trimmed, "❌"):
+			w.inError = true
+			kept.WriteString(line)
+		case w.inError && strings.HasPrefix(line, "   "):
+			kept.WriteString(line)
+		default:
+			w.inError = false
+		}
+	}
+
+	if kept.Len() > 0 {
+		if _, err := io.WriteString(w.out, kept.String()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,29 @@
+package operations
+
+import (
+	"errors"
+	"gmail-label-fixer/internal/gmail"
+	"strconv"
+)
+
+// isQuotaExhausted reports whether err came from the client refusing a call over the quota budget
+func isQuotaExhausted(err error) bool {
+	return errors.Is(err, gmail.ErrQuotaBudgetExceeded)
+}
+
+// printQuotaUsed reports the estimated quota units the run consumed
+func (o *Operations) printQuotaUsed() {
+	o.printf("📈 Used ~%s quota units\n", groupThousands(o.client.QuotaUsed()))
+}
+
+// groupThousands formats n with comma thousands separators, e.g. 1,240
+func groupThousands(n int) string {
+	digits := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + groupThousands(-n)
+	}
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
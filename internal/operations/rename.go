@@ -0,0 +1,64 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"strings"
+)
+
+// RenameLabel performs a single explicit rename, bypassing period detection but keeping
+// the usual validation, conflict checks, retries, rate limiting, and journaling
+func (o *Operations) RenameLabel(ctx context.Context, from, to string) error {
+	o.printf("✏️  Renaming label: %s → %s\n", from, to)
+
+	if strings.TrimSpace(to) == "" {
+		return fmt.Errorf("target name cannot be empty")
+	}
+	if from == to {
+		return fmt.Errorf("label '%s' already has that name", from)
+	}
+
+	source, exists, err := o.findLabel(ctx, from)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("label '%s' not found", from)
+	}
+	if source.Type != "user" {
+		return fmt.Errorf("label '%s' is a %s label and cannot be renamed", from, source.Type)
+	}
+
+	parts := strings.Split(to, "/")
+	transformation := &analyzer.LabelTransformation{
+		OriginalLabel:   source.Name,
+		OriginalID:      source.Id,
+		HierarchyParts:  parts,
+		NestedStructure: to,
+	}
+	for i := 1; i < len(parts); i++ {
+		transformation.RequiredParents = append(transformation.RequiredParents, strings.Join(parts[:i], "/"))
+	}
+
+	messageCount, err := o.client.GetLabelMessageCount(ctx, source.Id)
+	if err != nil {
+		o.printf("   ⚠️  Warning: Could not count messages for label %s: %v\n", from, err)
+	} else {
+		transformation.MessageCount = messageCount
+	}
+
+	// An explicit rename never merges, whatever created the target
+	if target, exists, err := o.findLabel(ctx, to); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("target label '%s' already exists (ID: %s)", to, target.Id)
+	}
+
+	if err := o.processTransformation(ctx, transformation); err != nil {
+		return err
+	}
+
+	o.printf("✅ Success: %s → %s\n", from, to)
+	return nil
+}
@@ -0,0 +1,20 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestRenameLabelMatchesTrimmedName(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{{Id: "work", Name: "Work ", Type: "user"}})
+	fake.AddMessage("m1", "work")
+
+	if err := newTestOperations(fake).RenameLabel(context.Background(), "Work", "Office"); err != nil {
+		t.Fatalf("RenameLabel: %v", err)
+	}
+	if counts := labelCounts(t, fake); counts["Office"] != 1 {
+		t.Errorf("labels after rename = %v, want Office with 1 message", counts)
+	}
+}
@@ -0,0 +1,127 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	ReportJSON     = "json"     // Post-run report as a JSON document (default)
+	ReportMarkdown = "markdown" // Post-run report as Markdown, e.g. for a change ticket
+)
+
+// ReportFormats lists the supported values for the --report-format flag
+var ReportFormats = []string{ReportJSON, ReportMarkdown}
+
+// reportFailure is one failed label in the post-run report
+type reportFailure struct {
+	Label string `json:"label"`
+	Error string `json:"error"`
+}
+
+// runReport summarizes a fix run for the --report file
+type runReport struct {
+	GeneratedAt       time.Time       `json:"generatedAt"`
+	Outcome           string          `json:"outcome"`
+	Attempted         int             `json:"attempted"`
+	Succeeded         int             `json:"succeeded"`
+	Renamed           int             `json:"renamed"`
+	Deleted           int             `json:"deleted"`
+	Failed            int             `json:"failed"`
+	Skipped           int             `json:"skipped"`
+	MessagesPreserved int             `json:"messagesPreserved"`
+	Failures          []reportFailure `json:"failures"`
+}
+
+// newRunReport summarizes result; stepStopped tells whether the user quit at the --step prompt
+func newRunReport(ctx context.Context, result *batchResult, stepStopped bool) *runReport {
+	result.mu.Lock()
+	defer result.mu.Unlock()
+
+	report := &runReport{
+		GeneratedAt:       time.Now(),
+		Outcome:           "completed",
+		Attempted:         result.total,
+		Succeeded:         result.processed,
+		Renamed:           result.renamed,
+		Deleted:           result.deleted,
+		Failed:            len(result.failures),
+		Skipped:           result.skipped,
+		MessagesPreserved: result.messages,
+		Failures:          []reportFailure{},
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		report.Outcome = "interrupted"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		report.Outcome = "timed out"
+	case result.aborted:
+		report.Outcome = "aborted"
+	case result.quotaExhausted:
+		report.Outcome = "quota exhausted"
+	case result.throttled:
+		report.Outcome = "backoff exhausted"
+	case stepStopped:
+		report.Outcome = "stopped"
+	}
+	for _, failure := range result.failures {
+		report.Failures = append(report.Failures, reportFailure{Label: failure.Label, Error: failure.Err.Error()})
+	}
+	return report
+}
+
+// markdown renders the report as a Markdown document
+func (r *runReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Gmail Label Fixer Report\n\n")
+	fmt.Fprintf(&b, "Generated: %s\n\n", r.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Outcome | %s |\n", r.Outcome)
+	fmt.Fprintf(&b, "| Attempted | %d |\n", r.Attempted)
+	fmt.Fprintf(&b, "| Succeeded | %d |\n", r.Succeeded)
+	fmt.Fprintf(&b, "| Renamed | %d |\n", r.Renamed)
+	fmt.Fprintf(&b, "| Deleted | %d |\n", r.Deleted)
+	fmt.Fprintf(&b, "| Failed | %d |\n", r.Failed)
+	fmt.Fprintf(&b, "| Skipped | %d |\n", r.Skipped)
+	fmt.Fprintf(&b, "| Messages preserved | %d |\n", r.MessagesPreserved)
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(&b, "\n## Failures\n\n")
+		for _, failure := range r.Failures {
+			fmt.Fprintf(&b, "- `%s`: %s\n", failure.Label, failure.Error)
+		}
+	}
+	return b.String()
+}
+
+// writeReport writes the post-run summary to Config.ReportPath, if set. A report that
+// cannot be written is only a warning; the renames already happened.
+func (o *Operations) writeReport(ctx context.Context, result *batchResult) {
+	if o.config.ReportPath == "" {
+		return
+	}
+
+	report := newRunReport(ctx, result, o.stepStopped())
+	var data []byte
+	if o.config.ReportFormat == ReportMarkdown {
+		data = []byte(report.markdown())
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			o.printf("⚠️  Warning: Could not encode report: %v\n", err)
+			return
+		}
+		data = append(data, '\n')
+	}
+
+	if err := os.WriteFile(o.config.ReportPath, data, 0600); err != nil {
+		o.printf("⚠️  Warning: Could not write report %s: %v\n", o.config.ReportPath, err)
+		return
+	}
+	o.printf("📝 Report written to %s\n", o.config.ReportPath)
+}
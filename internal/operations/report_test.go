@@ -0,0 +1,33 @@
+package operations
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewRunReportOutcome(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		result      *batchResult
+		stepStopped bool
+		want        string
+	}{
+		{"completed", context.Background(), &batchResult{}, false, "completed"},
+		{"interrupted", cancelled, &batchResult{}, false, "interrupted"},
+		{"fail fast", context.Background(), &batchResult{aborted: true}, false, "aborted"},
+		{"quota budget", context.Background(), &batchResult{quotaExhausted: true}, false, "quota exhausted"},
+		{"max total backoff", context.Background(), &batchResult{throttled: true}, false, "backoff exhausted"},
+		{"step quit", context.Background(), &batchResult{}, true, "stopped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRunReport(tt.ctx, tt.result, tt.stepStopped).Outcome; got != tt.want {
+				t.Errorf("Outcome = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
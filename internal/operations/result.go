@@ -0,0 +1,29 @@
+package operations
+
+// FailedLabel is a label a fix run could not convert
+type FailedLabel struct {
+	Label string
+	Err   error
+}
+
+// Result is the outcome of a fix run, for callers that need more than the printed summary
+type Result struct {
+	Succeeded         int // labels renamed or, with --delete-empty, deleted
+	Failed            []FailedLabel
+	Skipped           int // labels left out of the run for any reason
+	MessagesPreserved int // messages carried by the renamed labels
+}
+
+// result snapshots the batch as a Result
+func (r *batchResult) result() *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	failed := make([]FailedLabel, len(r.failures))
+	copy(failed, r.failures)
+	return &Result{
+		Succeeded:         r.processed,
+		Failed:            failed,
+		Skipped:           r.skipped,
+		MessagesPreserved: r.messages,
+	}
+}
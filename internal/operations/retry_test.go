@@ -0,0 +1,83 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetryWithBackoffStopsOnConflicts(t *testing.T) {
+	tests := []struct {
+		code      int
+		wantCalls int
+	}{
+		{http.StatusConflict, 1},
+		{http.StatusPreconditionFailed, 1},
+		{http.StatusBadRequest, 1},
+		{http.StatusServiceUnavailable, 2},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			ops := newTestOperations(newFakeService(nil))
+			calls := 0
+			_ = ops.retryWithBackoff(context.Background(), func() error {
+				calls++
+				if calls == 1 {
+					return retryNowError(tt.code)
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("operation called %d times after a %d, want %d", calls, tt.code, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoffHonorsRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "10")
+	throttled := &googleapi.Error{Code: http.StatusTooManyRequests, Header: header}
+
+	if delay, ok := retryAfterDelay(throttled); !ok || delay != 10*time.Second {
+		t.Errorf("retryAfterDelay = %v, %v, want 10s, true", delay, ok)
+	}
+
+	// Give up during the wait, the printed delay shows what the retry would have waited
+	var output strings.Builder
+	ops := newTestOperations(newFakeService(nil), func(config *Config) { config.Output = &output })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := ops.retryWithBackoff(ctx, func() error { return throttled })
+	if err == nil {
+		t.Fatal("retryWithBackoff succeeded, want it to give up waiting")
+	}
+	if !strings.Contains(output.String(), "waiting 10s before retry") {
+		t.Errorf("backoff did not wait for Retry-After:\n%s", output.String())
+	}
+}
+
+func TestRetryWithBackoffRetriesWrapped429(t *testing.T) {
+	wrapped := fmt.Errorf("failed to rename label Label_1 to Work/Acme: %w", retryNowError(http.StatusTooManyRequests))
+	if !isRetryableError(wrapped) {
+		t.Fatalf("isRetryableError(%v) = false, want true", wrapped)
+	}
+
+	ops := newTestOperations(newFakeService(nil))
+	calls := 0
+	err := ops.retryWithBackoff(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return wrapped
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("retryWithBackoff returned %v after %d calls, want success after 2", err, calls)
+	}
+}
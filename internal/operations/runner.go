@@ -0,0 +1,335 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"gmail-label-fixer/internal/gmail"
+	"sort"
+	"sync"
+	"time"
+)
+
+// labelTiming records how long one label took, including retries and rate limit delays
+type labelTiming struct {
+	Label    string
+	Duration time.Duration
+}
+
+// slowestLabelsShown is how many labels the --timings report lists
+const slowestLabelsShown = 5
+
+// batchResult aggregates the outcome of processing a set of transformations
+type batchResult struct {
+	mu        sync.Mutex
+	total     int
+	started   int
+	processed int
+	renamed   int
+	deleted   int
+	tooSmall  int // labels left alone for having fewer than Config.MinMessages messages
+	skipped   int // labels left out of the run for any reason, including tooSmall
+	messages  int // messages carried by successfully renamed labels
+	completed []string
+	failures  []FailedLabel
+	// quotaExhausted is set once the client refuses a call over Config quota budget
+	quotaExhausted bool
+	// aborted is set by the first failure when Config.FailFast is set
+	aborted bool
+	// throttled is set once retries are refused for exceeding Config.MaxTotalBackoff
+	throttled bool
+	timings   []labelTiming
+}
+
+// time records how long a label took to process
+func (r *batchResult) time(label string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timings = append(r.timings, labelTiming{Label: label, Duration: duration})
+}
+
+// nextIndex returns the 1-based position of the next transformation to start
+func (r *batchResult) nextIndex() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started++
+	return r.started
+}
+
+func (r *batchResult) succeed(transformation *analyzer.LabelTransformation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processed++
+	r.renamed++
+	r.messages += transformation.MessageCount
+	r.completed = append(r.completed, transformation.OriginalLabel+" → "+transformation.NestedStructure)
+}
+
+func (r *batchResult) succeedDelete(transformation *analyzer.LabelTransformation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processed++
+	r.deleted++
+	r.completed = append(r.completed, transformation.OriginalLabel+" (deleted)")
+}
+
+func (r *batchResult) fail(label string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, FailedLabel{Label: label, Err: err})
+	if isQuotaExhausted(err) {
+		r.quotaExhausted = true
+	}
+	if errors.Is(err, ErrBackoffBudgetExceeded) {
+		r.throttled = true
+	}
+}
+
+// decline records a transformation the user chose to skip in step mode
+func (r *batchResult) decline() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// abort stops the run after a failure under --fail-fast
+func (r *batchResult) abort() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aborted = true
+}
+
+// halted reports whether the run must stop handing out work: its quota or backoff budget ran
+// out, or it was aborted
+func (r *batchResult) halted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quotaExhausted || r.throttled || r.aborted
+}
+
+func (r *batchResult) outOfQuota() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quotaExhausted
+}
+
+// err returns the run's outcome: the context error if it was cut short, the quota or backoff
+// error if that budget ran out, ErrAborted with the first failure under --fail-fast, ErrLabelsFailed if any
+// label failed
+func (r *batchResult) err(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if r.quotaExhausted {
+		return fmt.Errorf("stopped after %d of %d labels: %w", r.processed, r.total, gmail.ErrQuotaBudgetExceeded)
+	}
+	if r.throttled {
+		return fmt.Errorf("stopped after %d of %d labels: %w", r.processed, r.total, ErrBackoffBudgetExceeded)
+	}
+	if r.aborted {
+		failure := r.failures[0]
+		return fmt.Errorf("%w after %d of %d labels: %s: %w", ErrAborted, r.processed, r.total, failure.Label, failure.Err)
+	}
+	if len(r.failures) > 0 {
+		return fmt.Errorf("%d of %d labels failed: %w", len(r.failures), r.total, ErrLabelsFailed)
+	}
+	return nil
+}
+
+// groupByDepth splits transformations into levels of equal hierarchy depth, shallowest first.
+// Every parent is therefore renamed in an earlier level than its children.
+func groupByDepth(transformations []*analyzer.LabelTransformation) [][]*analyzer.LabelTransformation {
+	levels := make(map[int][]*analyzer.LabelTransformation)
+	for _, transformation := range transformations {
+		depth := len(transformation.HierarchyParts)
+		levels[depth] = append(levels[depth], transformation)
+	}
+
+	var depths []int
+	for depth := range levels {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	var grouped [][]*analyzer.LabelTransformation
+	for _, depth := range depths {
+		level := levels[depth]
+		sort.Slice(level, func(i, j int) bool {
+			return level[i].OriginalLabel < level[j].OriginalLabel
+		})
+		grouped = append(grouped, level)
+	}
+	return grouped
+}
+
+// processTransformations applies transformations level by level, running up to
+// Config.Concurrency renames in parallel within each level
+func (o *Operations) processTransformations(ctx context.Context, transformations []*analyzer.LabelTransformation) *batchResult {
+	result := &batchResult{total: len(transformations)}
+
+	workers := o.config.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Replace the scrolling per-label lines with a progress bar on terminals
+	if isTerminal(o.output()) && len(transformations) > 1 && o.step == nil {
+		o.progress = newProgressBar(o.output(), len(transformations))
+		defer func() {
+			o.progress.finish()
+			o.progress = nil
+		}()
+	}
+
+	for _, level := range groupByDepth(transformations) {
+		queue := make(chan *analyzer.LabelTransformation)
+		var wg sync.WaitGroup
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for transformation := range queue {
+					o.processOne(ctx, transformation, result)
+				}
+			}()
+		}
+
+		for _, transformation := range level {
+			// Stop handing out work once the run is cancelled, times out, runs out of quota or fails fast
+			if ctx.Err() != nil || result.halted() || o.stepStopped() {
+				break
+			}
+			queue <- transformation
+		}
+		close(queue)
+		wg.Wait()
+
+		if ctx.Err() != nil || result.halted() || o.stepStopped() {
+			break
+		}
+	}
+
+	return result
+}
+
+// processOne applies a single transformation and records its outcome
+func (o *Operations) processOne(ctx context.Context, transformation *analyzer.LabelTransformation, result *batchResult) {
+	o.detailf("\n[%d/%d] Processing: %s\n", result.nextIndex(), result.total, transformation.OriginalLabel)
+	o.logger().Debug("processing label", "label_id", transformation.OriginalID, "from", transformation.OriginalLabel, "to", transformation.NestedStructure)
+
+	switch o.approveStep(transformation) {
+	case stepSkip:
+		o.println("   ⏭️  Skipped")
+		result.decline()
+		return
+	case stepQuit:
+		return
+	}
+
+	deleting := o.shouldDelete(transformation)
+	start := time.Now()
+	var err error
+	if deleting {
+		err = o.deleteEmptyLabel(ctx, transformation)
+	} else {
+		err = o.processTransformation(ctx, transformation)
+	}
+	result.time(transformation.OriginalLabel, time.Since(start))
+	o.analyzer.ForgetCount(transformation.OriginalID)
+	if o.progress != nil {
+		defer o.progress.increment()
+	}
+	if err != nil {
+		o.printf("❌ Failed: %s: %v\n", transformation.OriginalLabel, err)
+		result.fail(transformation.OriginalLabel, err)
+		if o.config.FailFast {
+			result.abort()
+		}
+		return
+	}
+
+	if deleting {
+		result.succeedDelete(transformation)
+	} else {
+		result.succeed(transformation)
+	}
+	o.detailf("✅ Success: %s\n", o.describePlan(transformation))
+}
+
+// printBatchSummary reports the overall outcome, listing each failed label
+func (o *Operations) printBatchSummary(ctx context.Context, result *batchResult) {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		o.printf("\n🛑 Interrupted: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		o.printf("\n⏰ Timed out: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	case o.stepStopped():
+		o.printf("\n🛑 Stopped: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	case result.quotaExhausted:
+		o.printf("\n💸 Quota budget exhausted: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	case result.throttled:
+		o.printf("\n🐢 Backoff budget exhausted, Gmail kept throttling: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	case result.aborted:
+		o.printf("\n⛔ Aborted at the first failure: processed %d/%d labels\n", result.processed, result.total)
+		o.printCompleted(result)
+	default:
+		o.printf("\n🎉 Completed! Processed %d/%d labels successfully.\n", result.processed, result.total)
+	}
+
+	if result.deleted > 0 || result.tooSmall > 0 {
+		o.printf("   Renamed: %d, deleted empty: %d, skipped too small: %d\n", result.renamed, result.deleted, result.tooSmall)
+	}
+
+	if len(result.failures) > 0 {
+		o.printf("\n❌ %d labels failed:\n", len(result.failures))
+		for _, failure := range result.failures {
+			o.printf("   - %s: %v\n", failure.Label, failure.Err)
+		}
+	}
+
+	if o.config.Timings {
+		o.printSlowest(result)
+	}
+
+	o.printCopied()
+	o.printQuotaUsed()
+}
+
+// printSlowest lists the labels that took longest to process, slowest first
+func (o *Operations) printSlowest(result *batchResult) {
+	if len(result.timings) == 0 {
+		return
+	}
+
+	timings := make([]labelTiming, len(result.timings))
+	copy(timings, result.timings)
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	if len(timings) > slowestLabelsShown {
+		timings = timings[:slowestLabelsShown]
+	}
+
+	o.printf("\n⏱️  Slowest %d labels:\n", len(timings))
+	for _, timing := range timings {
+		o.printf("   - %s: %v\n", timing.Label, timing.Duration.Round(time.Millisecond))
+	}
+}
+
+// printCompleted lists the renames that finished before a run was cut short
+func (o *Operations) printCompleted(result *batchResult) {
+	if len(result.completed) == 0 {
+		return
+	}
+	o.println("   Completed renames:")
+	for _, rename := range result.completed {
+		o.printf("   - %s\n", rename)
+	}
+}
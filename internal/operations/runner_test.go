@@ -0,0 +1,49 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gmail-label-fixer/internal/gmail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+func TestFixAllLabelsStopsWhenMergeHitsQuotaBudget(t *testing.T) {
+	fake := newFakeService([]*gmailAPI.Label{
+		{Id: "acme-old", Name: "Work.Acme", Type: "user"},
+		{Id: "acme", Name: "Work/Acme", Type: "user"},
+		{Id: "beta-old", Name: "Work.Beta", Type: "user"},
+		{Id: "beta", Name: "Work/Beta", Type: "user"},
+	})
+	fake.AddMessage("m1", "acme-old")
+	fake.AddMessage("m2", "beta-old")
+	fake.Errors["BatchModifyMessages"] = []error{fmt.Errorf("users.messages.batchModify: %w", gmail.ErrQuotaBudgetExceeded)}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	ops := newTestOperations(fake, func(config *Config) {
+		config.OnConflict = OnConflictMerge
+		config.ReportPath = reportPath
+	})
+	if _, err := ops.FixAllLabels(context.Background()); err == nil {
+		t.Fatal("FixAllLabels succeeded, want the quota budget to stop it")
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	var report runReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parsing report: %v", err)
+	}
+	if report.Outcome != "quota exhausted" {
+		t.Errorf("Outcome = %q, want %q", report.Outcome, "quota exhausted")
+	}
+	if calls := fake.Calls["BatchModifyMessages"]; calls != 1 {
+		t.Errorf("BatchModifyMessages called %d times, want the run to stop after the first", calls)
+	}
+}
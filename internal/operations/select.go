@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gmail-label-fixer/internal/analyzer"
+)
+
+// parseSelection turns input like "1,3,5-8" into sorted, distinct indexes into a list of n
+// entries. Numbers are 1-based as shown; "all" selects every entry.
+func parseSelection(input string, n int) ([]int, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "all") {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	chosen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q: not a number", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("invalid selection %q: not a number", part)
+			}
+		}
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid selection %q: choose between 1 and %d", part, n)
+		}
+		for i := first; i <= last; i++ {
+			chosen[i-1] = true
+		}
+	}
+
+	var indexes []int
+	for i := range chosen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// FixSelected lists every period-separated label with its message count, asks which ones to
+// fix, and fixes only the chosen labels (not their children)
+func (o *Operations) FixSelected(ctx context.Context) (*Result, error) {
+	o.println("🔍 Finding period-separated labels...")
+
+	transformations, err := o.findLabelsMatching(ctx, func(string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if len(transformations) == 0 {
+		o.println("✅ No period-separated labels found!")
+		return nil, ErrNothingToProcess
+	}
+	sort.Slice(transformations, func(i, j int) bool {
+		return transformations[i].OriginalLabel < transformations[j].OriginalLabel
+	})
+
+	o.printf("\n📋 %d period-separated labels:\n", len(transformations))
+	width := len(strconv.Itoa(len(transformations)))
+	for i, transformation := range transformations {
+		o.printf("   %*d. %s (%d messages)\n", width, i+1, transformation.OriginalLabel, transformation.MessageCount)
+	}
+	o.println()
+
+	answer, ok := o.ask("Labels to fix (e.g. 1,3,5-8 or all): ")
+	if !ok {
+		return nil, fmt.Errorf("--select needs a selection on standard input")
+	}
+	indexes, err := parseSelection(answer, len(transformations))
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		o.println("🛑 Nothing selected. No labels were changed.")
+		return &Result{Skipped: len(transformations)}, nil
+	}
+
+	selected := make([]*analyzer.LabelTransformation, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, transformations[i])
+	}
+	o.printf("🔧 Fixing %d selected labels\n", len(selected))
+
+	result, err := o.fixSubtree(ctx, selected)
+	if result != nil {
+		result.Skipped += len(transformations) - len(selected)
+	}
+	return result, err
+}
@@ -0,0 +1,82 @@
+package operations
+
+import (
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"sort"
+	"strings"
+)
+
+const (
+	SortByName     = "name"     // Alphabetical by current label name (default)
+	SortByMessages = "messages" // By message count
+	SortByDepth    = "depth"    // By number of nested segments
+)
+
+// SortKeys lists the supported keys for the analyze --sort flag
+var SortKeys = []string{SortByName, SortByMessages, SortByDepth}
+
+// SortOrder selects how the transformations table is ordered
+type SortOrder struct {
+	Key        string
+	Descending bool
+}
+
+// ParseSortOrder parses a --sort value such as "messages" or "depth:desc"
+func ParseSortOrder(spec string) (SortOrder, error) {
+	key, direction, hasDirection := strings.Cut(spec, ":")
+	order := SortOrder{Key: key}
+
+	known := false
+	for _, sortKey := range SortKeys {
+		if key == sortKey {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return SortOrder{}, fmt.Errorf("invalid --sort %q: key must be one of %s", spec, strings.Join(SortKeys, ", "))
+	}
+
+	if hasDirection {
+		switch direction {
+		case "asc":
+		case "desc":
+			order.Descending = true
+		default:
+			return SortOrder{}, fmt.Errorf("invalid --sort %q: direction must be asc or desc", spec)
+		}
+	}
+	return order, nil
+}
+
+// sortTransformations returns the transformations ordered by order, breaking ties by label name
+func sortTransformations(transformations map[string]*analyzer.LabelTransformation, order SortOrder) []*analyzer.LabelTransformation {
+	sorted := make([]*analyzer.LabelTransformation, 0, len(transformations))
+	for _, transformation := range transformations {
+		sorted = append(sorted, transformation)
+	}
+
+	compare := func(a, b *analyzer.LabelTransformation) int {
+		switch order.Key {
+		case SortByMessages:
+			return a.MessageCount - b.MessageCount
+		case SortByDepth:
+			return len(a.HierarchyParts) - len(b.HierarchyParts)
+		default:
+			return strings.Compare(a.OriginalLabel, b.OriginalLabel)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		result := compare(sorted[i], sorted[j])
+		if order.Descending {
+			result = -result
+		}
+		if result == 0 {
+			return sorted[i].OriginalLabel < sorted[j].OriginalLabel
+		}
+		return result < 0
+	})
+	return sorted
+}
@@ -0,0 +1,84 @@
+package operations
+
+import (
+	"errors"
+	"gmail-label-fixer/internal/analyzer"
+	"strings"
+	"sync"
+)
+
+// stepAnswer is the user's decision for one transformation in step mode
+type stepAnswer int
+
+const (
+	stepApply stepAnswer = iota
+	stepSkip
+	stepQuit
+)
+
+// stepper tracks the answers given so far in step mode
+type stepper struct {
+	mu   sync.Mutex
+	all  bool // apply every remaining transformation without asking
+	quit bool // stop the run, leaving remaining labels untouched
+}
+
+// errStepNotInteractive is returned when step mode is requested without a terminal to answer from
+var errStepNotInteractive = errors.New("--step needs an interactive terminal to read answers from")
+
+// checkStep refuses step mode when its prompts cannot be answered
+func (o *Operations) checkStep() error {
+	if o.step != nil && !o.isInteractive() {
+		return errStepNotInteractive
+	}
+	return nil
+}
+
+// approveStep asks whether to apply a transformation, unless step mode is off or the user chose all or quit
+func (o *Operations) approveStep(transformation *analyzer.LabelTransformation) stepAnswer {
+	if o.step == nil {
+		return stepApply
+	}
+
+	// One prompt at a time, even when renames run concurrently
+	o.step.mu.Lock()
+	defer o.step.mu.Unlock()
+
+	switch {
+	case o.step.quit:
+		return stepQuit
+	case o.step.all:
+		return stepApply
+	}
+
+	for {
+		answer, ok := o.ask("   " + o.describePlan(transformation) + "\n   [y]es / [n]o skip / [a]ll remaining / [q]uit: ")
+		if !ok {
+			o.step.quit = true
+			return stepQuit
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return stepApply
+		case "n", "no":
+			return stepSkip
+		case "a", "all":
+			o.step.all = true
+			return stepApply
+		case "q", "quit":
+			o.step.quit = true
+			return stepQuit
+		}
+	}
+}
+
+// stepStopped reports whether the user quit step mode
+func (o *Operations) stepStopped() bool {
+	if o.step == nil {
+		return false
+	}
+	o.step.mu.Lock()
+	defer o.step.mu.Unlock()
+	return o.step.quit
+}
@@ -0,0 +1,130 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"gmail-label-fixer/internal/analyzer"
+	"io"
+	"sort"
+	"strings"
+
+	gmailAPI "google.golang.org/api/gmail/v1"
+)
+
+// labelTree is a label hierarchy keyed by segment name; leaves are empty trees
+type labelTree map[string]labelTree
+
+// add inserts a nested label name, creating its parents as needed
+func (t labelTree) add(name string) {
+	node := t
+	for _, segment := range strings.Split(name, "/") {
+		child, exists := node[segment]
+		if !exists {
+			child = labelTree{}
+			node[segment] = child
+		}
+		node = child
+	}
+}
+
+// treeDiff holds the user label hierarchy before and after the planned transformations
+type treeDiff struct {
+	Before labelTree `json:"before"`
+	After  labelTree `json:"after"`
+}
+
+// newTreeDiff builds the current user label tree and the tree the transformations would produce
+func newTreeDiff(labels map[string]*gmailAPI.Label, transformations map[string]*analyzer.LabelTransformation) *treeDiff {
+	diff := &treeDiff{Before: labelTree{}, After: labelTree{}}
+	for name, label := range labels {
+		if label.Type != "user" {
+			continue
+		}
+		diff.Before.add(name)
+
+		if transformation, renamed := transformations[name]; renamed && transformation.NestedStructure != "" {
+			diff.After.add(transformation.NestedStructure)
+		} else {
+			diff.After.add(name)
+		}
+	}
+	return diff
+}
+
+// writeTreeDiff encodes the before/after label trees as an indented JSON document
+func writeTreeDiff(w io.Writer, result *analyzer.AnalysisResult) error {
+	data, err := json.MarshalIndent(newTreeDiff(result.ExistingLabels, result.Transformations), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode label trees: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// countTree is a node of the proposed hierarchy, with the messages of the label it stands for
+type countTree struct {
+	children map[string]*countTree
+	messages int
+	target   bool // a transformation renames a label to this node
+}
+
+// displayTree prints the proposed nested names as an indented tree, with message counts on the
+// labels being renamed
+func displayTree(w io.Writer, transformations map[string]*analyzer.LabelTransformation) {
+	root := &countTree{children: map[string]*countTree{}}
+	for _, transformation := range transformations {
+		if transformation.NestedStructure == "" {
+			continue
+		}
+		node := root
+		for _, segment := range strings.Split(transformation.NestedStructure, "/") {
+			child, exists := node.children[segment]
+			if !exists {
+				child = &countTree{children: map[string]*countTree{}}
+				node.children[segment] = child
+			}
+			node = child
+		}
+		node.target = true
+		node.messages += transformation.MessageCount
+	}
+
+	fmt.Fprintln(w)
+	for _, name := range sortedChildren(root) {
+		child := root.children[name]
+		fmt.Fprintln(w, child.describe(name))
+		child.print(w, "")
+	}
+	fmt.Fprintln(w)
+}
+
+// print writes the children of t with box-drawing branches, indented by prefix
+func (t *countTree) print(w io.Writer, prefix string) {
+	names := sortedChildren(t)
+	for i, name := range names {
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		child := t.children[name]
+		fmt.Fprintln(w, prefix+branch+child.describe(name))
+		child.print(w, prefix+indent)
+	}
+}
+
+// describe formats a node's name, with its message count when a label is renamed to it
+func (t *countTree) describe(name string) string {
+	if !t.target {
+		return name
+	}
+	return fmt.Sprintf("%s (%d messages)", name, t.messages)
+}
+
+func sortedChildren(t *countTree) []string {
+	names := make([]string, 0, len(t.children))
+	for name := range t.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,37 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Verify checks that no processable period-separated labels remain, e.g. after fix --all.
+// Skipped labels are reported but don't fail the check.
+func (o *Operations) Verify(ctx context.Context) error {
+	analysis, err := o.client.FindPeriodSeparatedLabelsWithAnalysis(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list labels: %v", err)
+	}
+
+	if len(analysis.SkippedLabels) > 0 {
+		o.printf("⏭️  Ignoring %d skipped labels\n", len(analysis.SkippedLabels))
+	}
+
+	if len(analysis.ProcessableLabels) == 0 {
+		o.println("✅ Verified: no period-separated labels remain")
+		return nil
+	}
+
+	var remaining []string
+	for _, label := range analysis.ProcessableLabels {
+		remaining = append(remaining, label.Name)
+	}
+	sort.Strings(remaining)
+
+	o.printf("❌ %d period-separated labels remain:\n", len(remaining))
+	for _, name := range remaining {
+		o.printf("   - %s\n", name)
+	}
+	return fmt.Errorf("%d period-separated labels remain", len(remaining))
+}
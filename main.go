@@ -1,9 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"gmail-label-fixer/internal/analyzer"
 	"gmail-label-fixer/internal/auth"
 	"gmail-label-fixer/internal/gmail"
 	"gmail-label-fixer/internal/operations"
@@ -11,10 +22,88 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var credentialsPath string
+var tokenPath string
+var profileName string
+var labelFilterPattern string
+var labelFilter *regexp.Regexp
+var skipNames []string
+var skipSystemNames []string
+var skipPrefixes []string
+var minSegments int
+var includeHidden bool
+var logLevel string
+var logFile string
+var logger *slog.Logger
+var timeout time.Duration
+var noColor bool
+var plain bool
+var quiet bool
+
 var rootCmd = &cobra.Command{
 	Use:   "gmail-label-fixer",
 	Short: "Fix Gmail label hierarchies from period-separated to nested format",
 	Long:  `A CLI tool to convert period-separated Gmail labels (like Vacations.2025.Mexico) into properly nested label hierarchies (Vacations/2025/Mexico).`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfigDefaults(cmd); err != nil {
+			return err
+		}
+
+		var err error
+		if logger, err = setupLogger(cmd); err != nil {
+			return err
+		}
+
+		if plain {
+			statusOutput = operations.NewPlainWriter(statusOutput)
+			errorOutput = operations.NewPlainWriter(errorOutput)
+		}
+		if quiet {
+			statusOutput = io.Discard
+		}
+
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cobra.OnFinalize(cancel)
+			cmd.SetContext(ctx)
+		}
+
+		if labelFilterPattern != "" {
+			filter, err := regexp.Compile(labelFilterPattern)
+			if err != nil {
+				return fmt.Errorf("invalid --label-filter %q: %w", labelFilterPattern, err)
+			}
+			labelFilter = filter
+		}
+		if minSegments < 2 {
+			return fmt.Errorf("--min-segments must be at least 2")
+		}
+		if analyzeConcurrency < 1 {
+			return fmt.Errorf("--analyze-concurrency must be at least 1")
+		}
+		if !slices.Contains(analyzer.CountModes, countMode) {
+			return fmt.Errorf("invalid --count-mode %q: must be one of %s", countMode, strings.Join(analyzer.CountModes, ", "))
+		}
+		if oauthPort < 0 || oauthPort > 65535 {
+			return fmt.Errorf("--oauth-port must be between 1 and 65535")
+		}
+		if flattenTop < 0 {
+			return fmt.Errorf("--flatten-top cannot be negative")
+		}
+		if strings.Contains(sanitize, "/") {
+			return fmt.Errorf("--sanitize replacement cannot contain /")
+		}
+		if mixedSeparators && (reverse || sanitize != "") {
+			return fmt.Errorf("--mixed-separators cannot be combined with --reverse or --sanitize")
+		}
+		if reverse && sanitize != "" {
+			return fmt.Errorf("--sanitize cannot be combined with --reverse")
+		}
+		if reverse && flattenTop > 0 {
+			return fmt.Errorf("--flatten-top cannot be combined with --reverse")
+		}
+		return nil
+	},
 }
 
 var analyzeCmd = &cobra.Command{
@@ -22,92 +111,722 @@ var analyzeCmd = &cobra.Command{
 	Short: "Analyze existing labels and show proposed changes (dry run)",
 	Long:  `Scan all Gmail labels and identify period-separated labels that can be converted to nested hierarchies. Shows what changes would be made without actually making them.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ops, err := setupOperations()
+		if !slices.Contains(operations.OutputFormats, outputFormat) {
+			return fmt.Errorf("invalid --output %q: must be one of %s", outputFormat, strings.Join(operations.OutputFormats, ", "))
+		}
+
+		// Keep stdout clean for machine-readable output
+		if (operations.IsMachineReadable(outputFormat) || treeDiff || debugGraph) && !quiet {
+			statusOutput = errorOutput
+		}
+
+		if sampleSize < 0 {
+			return fmt.Errorf("--sample cannot be negative")
+		}
+
+		order, err := operations.ParseSortOrder(sortSpec)
+		if err != nil {
+			return err
+		}
+
+		ops, err := setupReadOnlyOperations(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("setup failed: %w", err)
 		}
 
-		if err := ops.DryRun(); err != nil {
+		opts := operations.DryRunOptions{Output: outputFormat, Sort: order, Sample: sampleSize, GroupByRoot: groupByRoot, TreeDiff: treeDiff, OnlyConflicts: onlyConflicts, DebugGraph: debugGraph}
+		if outputFile != "" {
+			file, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("unable to create output file %s: %w", outputFile, err)
+			}
+			defer file.Close()
+			opts.Results = file
+		}
+
+		if err := ops.DryRun(cmd.Context(), opts); err != nil {
 			return fmt.Errorf("analysis failed: %w", err)
 		}
+		if outputFile != "" {
+			fmt.Fprintf(statusOutput, "💾 Analysis written to %s\n", outputFile)
+		}
 		return nil
 	},
 }
 
+var outputFormat string
+var sortSpec string
+var sampleSize int
+var outputFile string
+var groupByRoot bool
+var treeDiff bool
+var debugGraph bool
+var onlyConflicts bool
+
+// statusOutput receives progress and status messages
+var statusOutput io.Writer = os.Stdout
+
+// errorOutput receives warnings that must show even when status output is silenced
+var errorOutput io.Writer = os.Stderr
+
 var labelName string
 var fixAll bool
+var fixPrefix string
 var rateLimitDelay int
 var maxRetries int
+var delayJitter int
+var adaptiveRate bool
+var quotaBudget int
+var maxTotalBackoff time.Duration
+var journalPath string
+var assumeYes bool
+var stepMode bool
+var strictNames bool
+var timings bool
+var failFast bool
+var preserveOriginal bool
+var trimSegments bool
+var commit bool
+var confirmThreshold int
+var countMode string
+var concurrency int
+var analyzeConcurrency int
+var onConflict string
+var resume bool
+var reportPath string
+var reportFormat string
+var hiddenParents bool
+var minMessages int
+var deleteEmpty bool
+var flattenTop int
+var reverse bool
+var sanitize string
+var mixedSeparators bool
+var expectEmail string
+var verbose bool
+var oauthPort int
+var noBrowser bool
+var cacheTTL time.Duration
+var activeSince time.Duration
+var mapFile string
+var noCache bool
+var inputFile string
+var selectLabels bool
 
 var fixCmd = &cobra.Command{
 	Use:   "fix",
 	Short: "Fix label hierarchies",
-	Long:  `Convert period-separated labels to nested hierarchies. Use --label to fix a specific label (and all its children) or --all to fix all detected labels.`,
+	Long:  `Convert period-separated labels to nested hierarchies. Use --label to fix a specific label (and all its children), --prefix to fix every label under a dotted prefix, --input-file to fix the labels listed in a file, --select to pick labels from a numbered list, or --all to fix all detected labels.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Validate flags
-		if labelName != "" && fixAll {
-			return fmt.Errorf("cannot use both --label and --all flags together")
+		scopes := 0
+		for _, set := range []bool{labelName != "", fixAll, fixPrefix != "", inputFile != "", selectLabels} {
+			if set {
+				scopes++
+			}
+		}
+		if scopes > 1 {
+			return fmt.Errorf("use only one of --label, --prefix, --input-file, --select and --all")
+		}
+		if scopes == 0 {
+			return fmt.Errorf("must specify one of --label, --prefix, --input-file, --select or --all")
 		}
-		if labelName == "" && !fixAll {
-			return fmt.Errorf("must specify either --label or --all flag")
+		if concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+		if !slices.Contains(operations.OnConflictModes, onConflict) {
+			return fmt.Errorf("invalid --on-conflict %q: must be one of %s", onConflict, strings.Join(operations.OnConflictModes, ", "))
+		}
+		if !slices.Contains(operations.ReportFormats, reportFormat) {
+			return fmt.Errorf("invalid --report-format %q: must be one of %s", reportFormat, strings.Join(operations.ReportFormats, ", "))
 		}
 
-		ops, err := setupOperations()
+		ops, err := setupOperations(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("setup failed: %w", err)
 		}
 
+		// Failures from here on are outcomes reported through the exit code, not usage mistakes
+		cmd.SilenceUsage = true
+		var result *operations.Result
 		if fixAll {
-			if err := ops.FixAllLabels(); err != nil {
-				return fmt.Errorf("fix all failed: %w", err)
-			}
-			return nil
+			result, err = ops.FixAllLabels(cmd.Context())
+			err = wrapError("fix all failed", err)
+		} else if fixPrefix != "" {
+			result, err = ops.FixPrefix(cmd.Context(), fixPrefix)
+			err = wrapError("fix prefix failed", err)
+		} else if inputFile != "" {
+			result, err = ops.FixFromFile(cmd.Context(), inputFile)
+			err = wrapError("fix from file failed", err)
+		} else if selectLabels {
+			result, err = ops.FixSelected(cmd.Context())
+			err = wrapError("fix selected failed", err)
 		} else {
-			if err := ops.FixLabel(labelName); err != nil {
-				return fmt.Errorf("fix failed: %w", err)
+			result, err = ops.FixLabel(cmd.Context(), labelName)
+			err = wrapError("fix failed", err)
+		}
+		printResult(result)
+		return err
+	},
+}
+
+// wrapError prefixes err with context, leaving nil alone
+func wrapError(context string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", context, err)
+}
+
+// printResult prints the one-line tally of a fix run, if one ran
+func printResult(result *operations.Result) {
+	if result == nil {
+		return
+	}
+	fmt.Fprintf(statusOutput, "📊 Succeeded: %d, failed: %d, skipped: %d, messages preserved: %d\n",
+		result.Succeeded, len(result.Failed), result.Skipped, result.MessagesPreserved)
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the renames made by the most recent fix run",
+	Long:  `Read the rename journal written by fix and rename each label from the most recent run back to its original name. Labels that were modified since the fix are skipped with a warning.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(journalPath); err != nil {
+			return fmt.Errorf("cannot read journal %s: %w", journalPath, err)
+		}
+
+		ops, err := setupOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.Undo(cmd.Context()); err != nil {
+			return fmt.Errorf("undo failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var backupFile string
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Save a snapshot of every label's ID and name",
+	Long:  `Write the ID and name of every user label to a JSON file. Run it before a migration so restore can put every label back under its old name.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupReadOnlyOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.Backup(cmd.Context(), backupFile); err != nil {
+			return fmt.Errorf("backup failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Rename labels back to the names in a backup snapshot",
+	Long:  `Read a snapshot written by backup and rename every label whose name has changed since back to its snapshot name. Labels are matched by ID, so any renames in between are reverted too. Labels deleted since the snapshot are reported but cannot be recreated with their messages.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(backupFile); err != nil {
+			return fmt.Errorf("cannot read backup %s: %w", backupFile, err)
+		}
+
+		ops, err := setupOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.Restore(cmd.Context(), backupFile); err != nil {
+			return fmt.Errorf("restore failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var renameFrom string
+var renameTo string
+
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "Rename a single label explicitly",
+	Long:  `Rename one label to an exact new name, bypassing period detection. Conflict checking, rate limiting, retries, and the undo journal still apply.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.RenameLabel(cmd.Context(), renameFrom, renameTo); err != nil {
+			return fmt.Errorf("rename failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var listDepth int
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List period-separated labels without counting messages",
+	Long:  `Quickly list processable and skipped period-separated labels using a single API call. Use --depth to show only labels with at least N period-separated segments.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupReadOnlyOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.List(cmd.Context(), listDepth); err != nil {
+			return fmt.Errorf("list failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that no period-separated labels remain",
+	Long:  `Re-list labels after a fix and exit non-zero if any processable period-separated label still exists. Skipped labels do not fail the check, which makes this suitable for CI and automation.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupReadOnlyOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		// A failed check is a result, not a usage mistake
+		cmd.SilenceUsage = true
+		if err := ops.Verify(cmd.Context()); err != nil {
+			return fmt.Errorf("verify failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check credentials, token and Gmail access before a real run",
+	Long:  `Verify that the OAuth credentials parse and belong to a Desktop application client, that a token is cached and can be refreshed, that it grants the gmail.modify scope, and that a labels.list call succeeds. Never opens the browser.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		credPath, tokPath, err := resolveAuthPaths()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(statusOutput, "🩺 Checking setup...")
+		failed := 0
+		for _, check := range auth.Diagnose(cmd.Context(), credPath, tokPath) {
+			status, out := "✅", statusOutput
+			if !check.OK {
+				status = "❌"
+				failed++
+				if quiet {
+					out = errorOutput // Failures still show when everything else is silenced
+				}
 			}
-			return nil
+			fmt.Fprintf(out, "%s %s: %s\n", status, check.Name, check.Detail)
 		}
+
+		cmd.SilenceUsage = true
+		if failed > 0 {
+			return fmt.Errorf("%d setup checks failed", failed)
+		}
+		fmt.Fprintln(statusOutput, "🎉 Setup looks good!")
+		return nil
+	},
+}
+
+var cleanDryRun bool
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete empty period-separated labels",
+	Long:  `Find period-separated labels without any messages and delete them after confirmation. A label is kept while a label nested under it is not being deleted. Use --dry-run to only list what would be deleted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.Clean(cmd.Context(), cleanDryRun); err != nil {
+			return fmt.Errorf("clean failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var exportFile string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all labels to a CSV file",
+	Long:  `Write every Gmail label with its ID, type, message count, whether it is period-separated, and the proposed nested name to a CSV file for review before a migration.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ops, err := setupReadOnlyOperations(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("setup failed: %w", err)
+		}
+
+		if err := ops.Export(cmd.Context(), exportFile); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 	rootCmd.AddCommand(fixCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(doctorCmd)
+
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file providing flag defaults (default ~/.gmail-label-fixer.yaml)")
+	rootCmd.PersistentFlags().StringVar(&credentialsPath, "credentials", "", "Path to the OAuth client credentials file (env GMAIL_FIXER_CREDENTIALS, default credentials.json)")
+	rootCmd.PersistentFlags().StringVar(&tokenPath, "token", "", "Path to the cached OAuth token file (env GMAIL_FIXER_TOKEN, default token.json)")
+	rootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", 10*time.Minute, "Reuse message counts fetched within this long, e.g. by analyze right before fix")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Fetch every message count instead of using the on-disk count cache")
+	rootCmd.PersistentFlags().BoolVar(&noBrowser, "no-browser", false, "Print the sign-in URL with SSH port forwarding instructions instead of opening a browser")
+	rootCmd.PersistentFlags().IntVar(&oauthPort, "oauth-port", 0, "Fixed loopback port for the sign-in redirect http://127.0.0.1:PORT/callback (default a random free port)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Account profile name; keeps a separate token-<profile>.json per account")
+	rootCmd.PersistentFlags().StringVar(&expectEmail, "expect-email", "", "Abort unless the authenticated account has this email address")
+	rootCmd.PersistentFlags().StringVar(&labelFilterPattern, "label-filter", "", "Only process labels whose name matches this regular expression")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print every Gmail API call with its target and elapsed time")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing but errors, which go to stderr")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also disabled by NO_COLOR or when output is not a terminal)")
+	rootCmd.PersistentFlags().BoolVar(&plain, "plain", false, "Replace status emoji with ASCII tags like [OK], [WARN] and [ERR], for CI logs")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort the whole operation after this long, e.g. 30m (0 disables)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Structured log level: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Append structured JSON logs to this file")
+	rootCmd.PersistentFlags().StringVar(&mapFile, "map-file", "", "JSON or CSV file of explicit original → nested name renames, overriding the derived names")
+	rootCmd.PersistentFlags().DurationVar(&activeSince, "active-since", 0, "Only process labels with a message received within this long, e.g. 2160h for 90 days (costs 10 quota units per label)")
+	rootCmd.PersistentFlags().IntVar(&minSegments, "min-segments", 2, "Only process labels with at least this many period-separated segments")
+	rootCmd.PersistentFlags().BoolVar(&reverse, "reverse", false, "Convert nested labels (A/B/C) back to period-separated names (A.B.C)")
+	rootCmd.PersistentFlags().StringVar(&countMode, "count-mode", analyzer.CountModeMessages, "Count each label's "+strings.Join(analyzer.CountModes, " or ")+"; threads match Gmail's conversation view but cost more quota")
+	rootCmd.PersistentFlags().IntVar(&analyzeConcurrency, "analyze-concurrency", 1, "Number of label message counts to fetch in parallel during analysis")
+	rootCmd.PersistentFlags().BoolVar(&commit, "commit", false, "Apply the changes fix plans; without it fix only previews them (set commit: true in the config file to always apply)")
+	rootCmd.PersistentFlags().BoolVar(&trimSegments, "trim-segments", false, "Remove stray spaces around each segment, e.g. Work. Projects .X becomes Work/Projects/X")
+	rootCmd.PersistentFlags().BoolVar(&mixedSeparators, "mixed-separators", false, "Split period-separated labels at / as well, e.g. Work.Projects/Acme becomes Work/Projects/Acme")
+	rootCmd.PersistentFlags().StringVar(&sanitize, "sanitize", "", "Replace a / inside a period-separated segment with this string, e.g. - turns Projects.A/B into Projects/A-B")
+	rootCmd.PersistentFlags().IntVar(&flattenTop, "flatten-top", 0, "Drop this many leading segments from every nested name, e.g. 1 turns Receipts.2024 into 2024")
+	rootCmd.PersistentFlags().StringArrayVar(&skipNames, "skip", nil, "Exact label name to leave untouched (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&includeHidden, "include-hidden", false, "Also process labels hidden from the Gmail label list")
+	rootCmd.PersistentFlags().StringArrayVar(&skipPrefixes, "skip-prefix", []string{"[Gmail].", "[Imap]."}, "Skip every label starting with this prefix (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&skipSystemNames, "skip-system", nil, "Additional system label name to always skip, e.g. \"[Gmail].Sent Mail\" (repeatable)")
+
+	// Analyze command flags
+	analyzeCmd.Flags().BoolVar(&onlyConflicts, "only-conflicts", false, "Only list transformations with a conflict (existing target or parent, invalid name, collision)")
+	analyzeCmd.Flags().BoolVar(&debugGraph, "debug-graph", false, "Print the label → parent dependency graph in Graphviz DOT format, with the processing order, instead of the table")
+	analyzeCmd.Flags().BoolVar(&treeDiff, "dry-run-json-diff", false, "Print the label tree before and after the fix as JSON {before, after} instead of the table")
+	analyzeCmd.Flags().BoolVar(&groupByRoot, "group-by-root", false, "Show a separate table per top-level label, with a message subtotal each")
+	analyzeCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the table or JSON/YAML document to this file instead of stdout")
+	analyzeCmd.Flags().IntVar(&sampleSize, "sample", 0, "Only analyze the first N period-separated labels by name, for a quick check of a large mailbox")
+	analyzeCmd.Flags().StringVar(&sortSpec, "sort", operations.SortByName, "Table order: "+strings.Join(operations.SortKeys, ", ")+", with optional :desc (e.g. messages:desc)")
+	analyzeCmd.Flags().StringVarP(&outputFormat, "output", "o", operations.OutputTable, "Output format: "+strings.Join(operations.OutputFormats, ", "))
 
 	// Fix command flags
-	fixCmd.Flags().StringVarP(&labelName, "label", "l", "", "Name of the specific label to fix (includes all children)")
+	fixCmd.Flags().StringVarP(&labelName, "label", "l", "", "Name of the specific label to fix (includes all children); * ? and [...] match within a segment, e.g. \"Work.*.Invoices\"")
 	fixCmd.Flags().BoolVar(&fixAll, "all", false, "Fix all period-separated labels")
-	fixCmd.Flags().IntVar(&rateLimitDelay, "rate-limit-delay", 200, "Delay between API calls in milliseconds")
-	fixCmd.Flags().IntVar(&maxRetries, "max-retries", 3, "Maximum number of retries for rate-limited requests")
+	fixCmd.Flags().StringVar(&inputFile, "input-file", "", "Fix the labels listed in this file, one name per line (includes all children, # starts a comment)")
+	fixCmd.Flags().BoolVar(&selectLabels, "select", false, "List all period-separated labels with their message counts and pick which to fix, e.g. 1,3,5-8")
+	fixCmd.Flags().StringVar(&fixPrefix, "prefix", "", "Fix every label starting with this dotted prefix, e.g. \"Work.\"")
+	addRateLimitFlags(fixCmd)
+	fixCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of labels to rename in parallel (parents are always renamed before children)")
+	fixCmd.Flags().StringVar(&onConflict, "on-conflict", operations.OnConflictFail, "What to do when the target label already exists: "+strings.Join(operations.OnConflictModes, ", "))
+	fixCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation prompt before fixing all labels")
+	fixCmd.Flags().IntVar(&confirmThreshold, "confirm-threshold", 0, "Only ask for confirmation when more than this many labels or messages would change (0 always asks)")
+	fixCmd.Flags().BoolVar(&preserveOriginal, "preserve-original", false, "Create each nested label and copy the messages into it, keeping the original label as a backup (costs more quota)")
+	fixCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop at the first label that fails instead of continuing with the rest")
+	fixCmd.Flags().BoolVar(&timings, "timings", false, "Time each label and list the five slowest at the end")
+	fixCmd.Flags().BoolVar(&strictNames, "strict-names", false, "Refuse to fix while existing labels have names that differ only in whitespace or case")
+	fixCmd.Flags().BoolVar(&stepMode, "step", false, "Ask before applying each rename: [y]es / [n]o skip / [a]ll remaining / [q]uit")
+	fixCmd.Flags().StringVar(&journalPath, "journal", operations.DefaultJournalFile, "Path of the rename journal used by undo")
+	fixCmd.Flags().BoolVar(&hiddenParents, "hidden-parents", false, "Create missing parent labels hidden from the label list")
+	fixCmd.Flags().IntVar(&minMessages, "min-messages", 0, "Leave labels with fewer messages than this untouched")
+	fixCmd.Flags().BoolVar(&deleteEmpty, "delete-empty", false, "Delete period-separated labels without messages instead of renaming them")
+	fixCmd.Flags().StringVar(&reportPath, "report", "", "Write a post-run summary to this file")
+	fixCmd.Flags().StringVar(&reportFormat, "report-format", operations.ReportJSON, "Report format: "+strings.Join(operations.ReportFormats, ", "))
+	fixCmd.Flags().BoolVar(&resume, "resume", false, "Skip labels the journal shows were already renamed and continue the last run")
+
+	// List command flags
+	listCmd.Flags().IntVar(&listDepth, "depth", 2, "Only show labels with at least this many period-separated segments")
+
+	// Export command flags
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List empty labels without deleting them")
+	cleanCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation prompt")
+	addRateLimitFlags(cleanCmd)
+
+	exportCmd.Flags().StringVarP(&exportFile, "file", "f", "labels.csv", "Path of the CSV file to write")
+
+	// Undo command flags
+	undoCmd.Flags().StringVar(&journalPath, "journal", operations.DefaultJournalFile, "Path of the rename journal to revert")
+	addRateLimitFlags(undoCmd)
+
+	// Backup and restore command flags
+	backupCmd.Flags().StringVarP(&backupFile, "file", "f", operations.DefaultBackupFile, "Path of the backup file to write")
+	restoreCmd.Flags().StringVarP(&backupFile, "file", "f", operations.DefaultBackupFile, "Path of the backup file to restore from")
+	restoreCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation prompt")
+	addRateLimitFlags(restoreCmd)
+
+	// Rename command flags
+	renameCmd.Flags().StringVar(&renameFrom, "from", "", "Current name of the label to rename")
+	renameCmd.Flags().StringVar(&renameTo, "to", "", "New name for the label (use / for nesting)")
+	renameCmd.Flags().StringVar(&journalPath, "journal", operations.DefaultJournalFile, "Path of the rename journal used by undo")
+	renameCmd.MarkFlagRequired("from")
+	renameCmd.MarkFlagRequired("to")
+	addRateLimitFlags(renameCmd)
+}
+
+// addRateLimitFlags registers the rate limiting and retry flags on commands that modify labels
+func addRateLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&rateLimitDelay, "rate-limit-delay", 200, "Delay between API calls in milliseconds")
+	cmd.Flags().IntVar(&maxRetries, "max-retries", 3, "Maximum number of retries for rate-limited requests")
+	cmd.Flags().IntVar(&delayJitter, "delay-jitter", 0, "Add a random delay of up to this many milliseconds between API calls")
+	cmd.Flags().BoolVar(&adaptiveRate, "adaptive-rate", false, "Start fast and tune the delay between calls from observed rate limiting (ignores --rate-limit-delay)")
+	cmd.Flags().DurationVar(&maxTotalBackoff, "max-total-backoff", 0, "Fail the run once waiting between retries adds up to this long across all labels, e.g. 10m (0 for no limit)")
+	cmd.Flags().IntVar(&quotaBudget, "quota-budget", 0, "Stop the run before it uses more than this many estimated Gmail API quota units (0 for no limit)")
 }
 
-func setupOperations() (*operations.Operations, error) {
-	fmt.Println("🔐 Authenticating with Gmail...")
+// setupOperations authenticates with the gmail.modify scope, for commands that change labels
+func setupOperations(ctx context.Context) (*operations.Operations, error) {
+	return setupOperationsWithScope(ctx, auth.ScopeModify)
+}
+
+// setupReadOnlyOperations authenticates with the narrower gmail.readonly scope
+func setupReadOnlyOperations(ctx context.Context) (*operations.Operations, error) {
+	return setupOperationsWithScope(ctx, auth.ScopeReadOnly)
+}
 
-	gmailService, err := auth.GetGmailService()
+func setupOperationsWithScope(ctx context.Context, scope string) (*operations.Operations, error) {
+	// The sign-in prompt must be seen even under --quiet, or a run without a token waits for nothing
+	auth.SetOutput(errorOutput)
+	auth.SetOAuthPort(oauthPort)
+	auth.SetNoBrowser(noBrowser)
+
+	var renames map[string]string
+	if mapFile != "" {
+		var err error
+		if renames, err = analyzer.LoadRenameMap(mapFile); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Fprintln(statusOutput, "🔐 Authenticating with Gmail...")
+
+	credPath, tokPath, err := resolveAuthPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	gmailService, err := auth.GetGmailService(ctx, credPath, tokPath, scope)
 	if err != nil {
 		return nil, fmt.Errorf("authentication failed: %v", err)
 	}
 
-	client := gmail.NewClient(gmailService)
+	var clientOptions []gmail.Option
+	if labelFilter != nil {
+		clientOptions = append(clientOptions, gmail.WithLabelFilter(labelFilter))
+	}
+	if len(skipNames) > 0 {
+		clientOptions = append(clientOptions, gmail.WithExcludedLabels(skipNames))
+	}
+	if minSegments > 2 {
+		clientOptions = append(clientOptions, gmail.WithMinSegments(minSegments))
+	}
+	if len(skipPrefixes) > 0 {
+		clientOptions = append(clientOptions, gmail.WithSkipPrefixes(skipPrefixes))
+	}
+	if len(skipSystemNames) > 0 {
+		clientOptions = append(clientOptions, gmail.WithSkipLabels(skipSystemNames))
+	}
+	if includeHidden {
+		clientOptions = append(clientOptions, gmail.WithIncludeHidden())
+	}
+	if reverse {
+		clientOptions = append(clientOptions, gmail.WithReverse())
+	}
+	if sanitize != "" || mixedSeparators {
+		clientOptions = append(clientOptions, gmail.WithAllowSlashes())
+	}
+	if verbose {
+		clientOptions = append(clientOptions, gmail.WithCallLog(statusOutput))
+	}
+	if quotaBudget > 0 {
+		clientOptions = append(clientOptions, gmail.WithQuotaBudget(quotaBudget))
+	}
+
+	client := gmail.NewClient(gmailService, clientOptions...)
+
+	// Show which account is about to be touched, so the wrong profile is easy to spot
+	profile, err := client.GetProfile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read account profile: %v", err)
+	}
+	fmt.Fprintf(statusOutput, "👤 Operating on: %s\n", profile.EmailAddress)
+	if expectEmail != "" && !strings.EqualFold(profile.EmailAddress, expectEmail) {
+		return nil, fmt.Errorf("authenticated as %s but --expect-email is %s", profile.EmailAddress, expectEmail)
+	}
 
 	// Configure rate limiting
 	config := &operations.Config{
-		RateLimitDelay: rateLimitDelay,
-		MaxRetries:     maxRetries,
+		RateLimitDelay:     rateLimitDelay,
+		MaxRetries:         maxRetries,
+		JournalPath:        journalPath,
+		Output:             statusOutput,
+		AssumeYes:          assumeYes,
+		Step:               stepMode,
+		StrictNames:        strictNames,
+		Timings:            timings,
+		FailFast:           failFast,
+		MaxTotalBackoff:    maxTotalBackoff,
+		PreserveOriginal:   preserveOriginal,
+		Preview:            !commit,
+		ConfirmThreshold:   confirmThreshold,
+		CountMode:          countMode,
+		Concurrency:        concurrency,
+		AnalyzeConcurrency: analyzeConcurrency,
+		OnConflict:         onConflict,
+		Logger:             logger,
+		Resume:             resume,
+		HiddenParents:      hiddenParents,
+		MinMessages:        minMessages,
+		DeleteEmpty:        deleteEmpty,
+		NoColor:            noColor,
+		Plain:              plain,
+		Quiet:              quiet,
+		ReportPath:         reportPath,
+		ReportFormat:       reportFormat,
+		ParseOptions:       analyzer.ParseOptions{FlattenTop: flattenTop, Reverse: reverse, SlashReplacement: sanitize, MixedSeparators: mixedSeparators, TrimSegments: trimSegments, Renames: renames},
+		DelayJitter:        delayJitter,
+		AdaptiveRate:       adaptiveRate,
+		ActiveSince:        activeSince,
+	}
+
+	if !noCache {
+		cache := analyzer.LoadCountCache(countCacheFile(), cacheTTL)
+		cache.SetAccount(profile.EmailAddress)
+		config.CountCache = cache
+		cobra.OnFinalize(func() {
+			if err := cache.Save(); err != nil {
+				fmt.Fprintf(errorOutput, "⚠️  Warning: %v\n", err)
+			}
+		})
 	}
 
 	ops := operations.NewOperationsWithConfig(client, config)
 
-	fmt.Println("✅ Authentication successful!")
+	fmt.Fprintln(statusOutput, "✅ Authentication successful!")
 
 	return ops, nil
 }
 
+// setupLogger builds the structured logger: JSON to --log-file when given, text on stderr when
+// only --log-level is set, and nothing otherwise so console output stays unchanged
+func setupLogger(cmd *cobra.Command) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid --log-level %q: must be one of debug, info, warn, error", logLevel)
+	}
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	if logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open log file %s: %w", logFile, err)
+		}
+		return slog.New(slog.NewJSONHandler(file, handlerOptions)), nil
+	}
+
+	if flagProvided(cmd, "log-level") {
+		return slog.New(slog.NewTextHandler(os.Stderr, handlerOptions)), nil
+	}
+
+	return slog.New(slog.NewTextHandler(io.Discard, nil)), nil
+}
+
+// resolveAuthPaths returns the credentials and token paths for the selected profile
+func resolveAuthPaths() (string, string, error) {
+	profileCredPath, profileTokenPath, err := auth.ProfilePaths(profileName)
+	if err != nil {
+		return "", "", err
+	}
+	credPath := resolvePath(credentialsPath, "GMAIL_FIXER_CREDENTIALS", profileCredPath)
+	tokPath := resolvePath(tokenPath, "GMAIL_FIXER_TOKEN", profileTokenPath)
+	return credPath, tokPath, nil
+}
+
+// countCacheFile returns the message count cache of the current profile
+func countCacheFile() string {
+	if name := auth.SanitizeProfile(profileName); name != "" {
+		return fmt.Sprintf("label-counts-%s.json", name)
+	}
+	return "label-counts.json"
+}
+
+// resolvePath picks a file path from the flag value, then the environment variable, then the default
+func resolvePath(flagValue, envVar, defaultValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if envValue := os.Getenv(envVar); envValue != "" {
+		return envValue
+	}
+	return defaultValue
+}
+
+// Process exit codes, so scripts can tell partial failures from fatal ones
+const (
+	exitError            = 1   // Setup, authentication, analysis or other fatal error
+	exitLabelsFailed     = 2   // The run finished but some labels failed
+	exitNothingToProcess = 3   // No period-separated labels matched
+	exitAborted          = 4   // --fail-fast stopped the run at the first failure
+	exitInterrupted      = 130 // Cancelled with Ctrl-C or SIGTERM
+)
+
+// exitCode maps a command error to the process exit code
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, operations.ErrAborted):
+		return exitAborted
+	case errors.Is(err, operations.ErrLabelsFailed):
+		return exitLabelsFailed
+	case errors.Is(err, operations.ErrNothingToProcess):
+		return exitNothingToProcess
+	default:
+		return exitError
+	}
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// The first Ctrl-C or SIGTERM cancels the run after the label in progress; a second one exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Fprintln(errorOutput, "\n🛑 Interrupt received, finishing the current label. Press Ctrl-C again to quit immediately.")
+		cancel()
+		<-signals
+		fmt.Fprintln(errorOutput, "🛑 Forced exit")
+		os.Exit(exitInterrupted)
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }